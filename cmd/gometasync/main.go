@@ -1,26 +1,107 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/Saba101/GoMetaSync/internal/codescan"
 	"github.com/Saba101/GoMetaSync/internal/collector"
 	"github.com/Saba101/GoMetaSync/internal/config"
+	"github.com/Saba101/GoMetaSync/internal/filter"
 	"github.com/Saba101/GoMetaSync/internal/generator"
+	"github.com/Saba101/GoMetaSync/internal/migrate"
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/policy"
+	"github.com/Saba101/GoMetaSync/internal/report"
 	"github.com/Saba101/GoMetaSync/internal/snapshot"
 )
 
 func main() {
-	mode := flag.String("mode", "snapshot", "snapshot | diff | generate")
+	mode := flag.String("mode", "snapshot", "snapshot | diff | compare | three-way | matrix | impact | usages | generate | migrate | ddl | apply\n(ddl only rebuilds schemas, tables, columns, constraints and indexes: no views, defaults, identity, sequences, functions, triggers or grants)")
 	cfgPath := flag.String("config", "configs/dev.yml", "config file path")
 	oldSnapPath := flag.String("old", "", "old snapshot path (for diff)")
 	newSnapPath := flag.String("new", "snapshots/dev-latest.json", "new snapshot output path")
 	outDir := flag.String("out", "generated_models", "output dir for generated structs")
+	exportSnap := flag.Bool("export-snapshot", false, "export the collection transaction snapshot (pg_export_snapshot) and record its id")
+	includeSchemas := flag.String("include-schema", "", "comma-separated schema patterns to include (glob, or re:<regexp>)")
+	excludeSchemas := flag.String("exclude-schema", "", "comma-separated schema patterns to exclude")
+	includeTables := flag.String("include-table", "", "comma-separated table patterns to include (table or schema.table)")
+	excludeTables := flag.String("exclude-table", "", "comma-separated table patterns to exclude")
+	includeColumns := flag.String("include-column", "", "comma-separated column patterns to include (column, table.column or schema.table.column)")
+	excludeColumns := flag.String("exclude-column", "", "comma-separated column patterns to exclude")
+	includeObjects := flag.String("include-object", "", "comma-separated object types to include: primary_key,unique,check,foreign_key,index")
+	excludeObjects := flag.String("exclude-object", "", "comma-separated object types to exclude")
+	detectRenames := flag.Bool("detect-renames", true, "report likely renames instead of a drop plus an add")
+	renameHintsPath := flag.String("rename-hints", "", "file of confirmed renames (<object> <old path> -> <new name>)")
+	matchByDef := flag.Bool("match-by-definition", false, "match constraints and indexes by definition, ignoring name differences")
+	showDefs := flag.Bool("show-definitions", false, "print raw and normalized definitions of changed check constraints and indexes")
+	ignorePath := flag.String("ignore", "", "diff suppressions file (default "+snapshot.DefaultSuppressionsFile+" if present)")
+	concurrently := flag.Bool("concurrently", false, "migrate: create and drop indexes CONCURRENTLY")
+	migrateOut := flag.String("migrate-out", "", "migrate, ddl: write the SQL to this file instead of stdout")
+	down := flag.Bool("down", false, "migrate: print the down (rollback) script instead of the up script")
+	migrateDir := flag.String("migrate-dir", "", "migrate: write versioned up/down migration files into this directory")
+	migrateFormat := flag.String("migrate-format", string(migrate.LayoutGolangMigrate), "migrate: file layout for --migrate-dir: golang-migrate | goose | atlas")
+	migrateName := flag.String("migrate-name", "sync", "migrate: name part of migration file names")
+	ddlDir := flag.String("ddl-dir", "", "ddl: write one file per object into this directory instead of a single script")
+	refSnapPath := flag.String("reference", "", "apply: snapshot the live databases are brought in line with")
+	approve := flag.Bool("approve", false, "apply: execute the plan instead of only printing it")
+	allowOps := flag.String("allow", "", "apply: comma-separated step kinds allowed to run, e.g. *_added (added to the config's apply.allow)")
+	denyOps := flag.String("deny", "", "apply: comma-separated step kinds never run, e.g. *_dropped (added to the config's apply.deny)")
+	lockTimeout := flag.Duration("lock-timeout", 0, "apply: lock_timeout for every step (default from config, else 5s)")
+	mappingPath := flag.String("mapping", "", "diff, compare: file mapping old database/schema names to new ones")
+	leftCfg := flag.String("left", "", "compare: config of the left (old) side")
+	rightCfg := flag.String("right", "", "compare: config of the right (new) side")
+	baseSnapPath := flag.String("base", "", "three-way: snapshot both sides started from, e.g. the last release")
+	oursSnapPath := flag.String("ours", "", "three-way: snapshot of our side, reported as left")
+	theirsSnapPath := flag.String("theirs", "", "three-way: snapshot of their side, reported as right")
+	mapDBs := flag.String("map-db", "", "compare: comma-separated left=right database name pairs, e.g. app_dev=app")
+	snapshotList := flag.String("snapshots", "", "matrix: comma-separated snapshot files, optionally name=path (default name: the snapshot's env)")
+	baseline := flag.String("baseline", "", "matrix: baseline environment (default: the last snapshot)")
+	format := flag.String("format", "", "diff, compare: text | json | markdown (default text); impact, usages: text | json; matrix: markdown | html | json (default markdown)")
+	reportOut := flag.String("report-out", "", "diff, compare, impact, usages, matrix: write the report to this file instead of stdout")
+	codeDir := flag.String("code", ".", "usages: root of the Go code to scan")
+	layout := flag.String("layout", string(generator.LayoutFlat), "generate, impact, usages: package layout: flat | database | schema")
+	pkgName := flag.String("package", generator.DefaultPackage, "generate, impact, usages: package name for the flat layout")
+	importPath := flag.String("import-path", "", "generate: import path of --out; adds import comments to the generated files")
+	initialisms := flag.String("initialisms", "", "generate, impact, usages: comma-separated initialisms written in upper case, added to the defaults (ID, URL, API, …)")
+	noDefaultInitialisms := flag.Bool("no-default-initialisms", false, "generate, impact, usages: use only --initialisms")
+	pluralNames := flag.Bool("plural-names", false, "generate, impact, usages: keep struct names plural like their tables (users → Users)")
+	dbPrefix := flag.Bool("db-prefix", false, "generate, impact, usages: prefix struct names with their database in the flat layout, for snapshots of several databases")
+	fieldOrder := flag.String("field-order", generator.FieldOrderOrdinal, "generate: struct field order: ordinal (table column order) | alphabetical")
+	noTypeCheck := flag.Bool("no-typecheck", false, "generate: skip type-checking the generated packages (they are still gofmt'ed)")
+	onCollision := flag.String("on-collision", generator.OnCollisionSuffix, "generate: what to do when tables, columns or files map to the same Go name: suffix | error")
 	flag.Parse()
+	genOpts := generator.Options{Layout: generator.Layout(*layout), Package: *pkgName, ImportPath: *importPath,
+		Initialisms: splitList(*initialisms), NoDefaultInitialisms: *noDefaultInitialisms, PluralNames: *pluralNames,
+		DatabasePrefix: *dbPrefix, FieldOrder: *fieldOrder, NoTypeCheck: *noTypeCheck, OnCollision: *onCollision}
 
-	cfg, err := config.LoadConfig(*cfgPath)
-	if err != nil {
-		panic(err)
+	cliRules := filter.Rules{
+		IncludeSchemas: splitList(*includeSchemas),
+		ExcludeSchemas: splitList(*excludeSchemas),
+		IncludeTables:  splitList(*includeTables),
+		ExcludeTables:  splitList(*excludeTables),
+		IncludeColumns: splitList(*includeColumns),
+		ExcludeColumns: splitList(*excludeColumns),
+		IncludeObjects: splitList(*includeObjects),
+		ExcludeObjects: splitList(*excludeObjects),
+	}
+
+	// compare reads its configs from --left and --right
+	cfg := &config.Config{}
+	if *mode != "compare" {
+		var err error
+		if cfg, err = config.LoadConfig(*cfgPath); err != nil {
+			panic(err)
+		}
 	}
 
 	// Build map[name]dsn
@@ -29,9 +110,62 @@ func main() {
 		dbMap[db.Name] = db.BuildDSN()
 	}
 
+	filters, err := cfg.FilterSet(cliRules)
+	if err != nil {
+		panic(err)
+	}
+
+	// loadPair loads the --old and --new snapshots, filtered
+	loadPair := func() (*models.Snapshot, *models.Snapshot) {
+		oldSnap, err := snapshot.LoadSnapshot(*oldSnapPath)
+		if err != nil {
+			panic(err)
+		}
+		newSnap, err := snapshot.LoadSnapshot(*newSnapPath)
+		if err != nil {
+			panic(err)
+		}
+		filters.Apply(oldSnap)
+		filters.Apply(newSnap)
+		return oldSnap, newSnap
+	}
+
+	// loadMapping reads --mapping, if any
+	loadMapping := func() snapshot.Mapping {
+		if *mappingPath == "" {
+			return snapshot.Mapping{}
+		}
+		m, err := snapshot.LoadMapping(*mappingPath)
+		if err != nil {
+			panic(err)
+		}
+		return m
+	}
+
+	diffOptions := func() snapshot.DiffOptions {
+		suppressions, err := loadSuppressions(*ignorePath)
+		if err != nil {
+			panic(err)
+		}
+		var hints []snapshot.RenameHint
+		if *renameHintsPath != "" {
+			if hints, err = snapshot.LoadRenameHints(*renameHintsPath); err != nil {
+				panic(err)
+			}
+		}
+		return snapshot.DiffOptions{
+			Suppressions:  suppressions,
+			DetectRenames: *detectRenames,
+			RenameHints:   hints,
+
+			MatchByDefinition: *matchByDef,
+			ShowDefinitions:   *showDefs,
+		}
+	}
+
 	switch *mode {
 	case "snapshot":
-		snap, err := collector.CollectSnapshot(cfg.Env, dbMap)
+		snap, err := collector.CollectSnapshot(cfg.Env, dbMap, collector.Options{ExportSnapshot: *exportSnap, Filters: filters})
 		if err != nil {
 			panic(err)
 		}
@@ -42,15 +176,296 @@ func main() {
 		return
 
 	case "diff":
-		oldSnap, err := snapshot.LoadSnapshot(*oldSnapPath)
+		oldSnap, newSnap := loadPair()
+		if err := loadMapping().Apply(oldSnap); err != nil {
+			panic(err)
+		}
+		opts := diffOptions()
+		printDiff(*reportOut, *format, snapshot.Compare(oldSnap, newSnap, opts), opts)
+		return
+
+	case "migrate":
+		oldSnap, newSnap := loadPair()
+		opts := diffOptions()
+		// suppressed drift is expected, so it isn't migrated either
+		changes, _, _ := snapshot.Suppress(snapshot.Compare(oldSnap, newSnap, opts), opts.Suppressions, time.Now())
+		up := migrate.Plan(oldSnap, newSnap, changes, migrate.Options{Concurrently: *concurrently})
+		downSteps := migrate.Plan(newSnap, oldSnap, snapshot.Invert(changes), migrate.Options{Concurrently: *concurrently, Down: true})
+
+		if *migrateDir != "" {
+			files, err := migrate.WriteFiles(*migrateDir, migrate.Layout(*migrateFormat), *migrateName, time.Now(), up, downSteps)
+			if err != nil {
+				panic(err)
+			}
+			for _, f := range files {
+				fmt.Println("✅ Migration written:", f)
+			}
+			if migrate.Layout(*migrateFormat) == migrate.LayoutAtlas && len(files) > 0 {
+				fmt.Println("ℹ️ Run `atlas migrate hash` to update atlas.sum")
+			}
+			return
+		}
+
+		steps := up
+		if *down {
+			steps = downSteps
+		}
+		if err := writeOutput(*migrateOut, func(w io.Writer) error { return migrate.Write(w, steps) }); err != nil {
+			panic(err)
+		}
+		if *migrateOut != "" {
+			fmt.Println("✅ Migration written:", *migrateOut)
+		}
+		return
+
+	case "ddl":
+		// Rebuild the schema of a snapshot file (the one you pass via --new)
+		snap, err := snapshot.LoadSnapshot(*newSnapPath)
 		if err != nil {
 			panic(err)
 		}
-		newSnap, err := snapshot.LoadSnapshot(*newSnapPath)
+		filters.Apply(snap)
+		steps := migrate.DDL(snap, migrate.Options{Concurrently: *concurrently})
+		if *ddlDir != "" {
+			files, err := migrate.WriteObjectFiles(*ddlDir, steps)
+			if err != nil {
+				panic(err)
+			}
+			fmt.Printf("✅ %d DDL files written into: %s\n", len(files), *ddlDir)
+			return
+		}
+		if err := writeOutput(*migrateOut, func(w io.Writer) error { return migrate.Write(w, steps) }); err != nil {
+			panic(err)
+		}
+		if *migrateOut != "" {
+			fmt.Println("✅ DDL written:", *migrateOut)
+		}
+		return
+
+	case "apply":
+		if *refSnapPath == "" {
+			panic("apply: --reference snapshot is required")
+		}
+		applyPolicy := cfg.Apply.Merge(policy.Policy{Allow: splitList(*allowOps), Deny: splitList(*denyOps), LockTimeout: *lockTimeout})
+		if err := applyPolicy.Validate(); err != nil {
+			panic(err)
+		}
+		ref, err := snapshot.LoadSnapshot(*refSnapPath)
+		if err != nil {
+			panic(err)
+		}
+		filters.Apply(ref)
+		for db := range ref.Databases {
+			if _, ok := dbMap[db]; !ok {
+				fmt.Printf("⚠️ Database %s is in the reference snapshot but not in %s; skipped\n", db, *cfgPath)
+				delete(ref.Databases, db)
+			}
+		}
+		live, err := collector.CollectSnapshot(cfg.Env, dbMap, collector.Options{Filters: filters})
+		if err != nil {
+			panic(err)
+		}
+
+		opts := diffOptions()
+		changes, _, _ := snapshot.Suppress(snapshot.Compare(live, ref, opts), opts.Suppressions, time.Now())
+		steps := migrate.Plan(live, ref, changes, migrate.Options{Concurrently: *concurrently})
+		if len(steps) == 0 {
+			fmt.Println("✅ Live databases already match the reference snapshot")
+			return
+		}
+		if err := migrate.Write(os.Stdout, steps); err != nil {
+			panic(err)
+		}
+
+		blocked := 0
+		for _, s := range steps {
+			if why := migrate.Blocked(applyPolicy, s); why != "" {
+				fmt.Printf("🚫 Blocked: %s %s (%s)\n", s.Kind, s.Path, why)
+				blocked++
+			}
+		}
+		if blocked > 0 {
+			fmt.Printf("\n❌ %d of %d steps are not allowed; nothing applied\n", blocked, len(steps))
+			os.Exit(1)
+		}
+		if !*approve {
+			fmt.Printf("\nℹ️ %d steps planned. Re-run with --approve to apply them.\n", len(steps))
+			return
+		}
+
+		byDB := map[string][]migrate.Step{}
+		for _, s := range steps {
+			byDB[s.DB] = append(byDB[s.DB], s)
+		}
+		for _, db := range slices.Sorted(maps.Keys(byDB)) {
+			fmt.Println("\n🚀 Applying to", db)
+			if err := migrate.Apply(context.Background(), dbMap[db], byDB[db], applyPolicy, os.Stdout); err != nil {
+				panic(err)
+			}
+		}
+		fmt.Println("✅ Applied", len(steps), "steps")
+		return
+
+	case "compare":
+		if *leftCfg == "" || *rightCfg == "" {
+			panic("compare: --left and --right configs are required")
+		}
+		// Merge copies the maps, so --map-db pairs can be added to them
+		mapping := loadMapping().Merge(snapshot.Mapping{})
+		for _, pair := range splitList(*mapDBs) {
+			l, r, ok := strings.Cut(pair, "=")
+			if !ok {
+				panic(fmt.Sprintf("compare: --map-db %q: want left=right", pair))
+			}
+			mapping.Databases[strings.TrimSpace(l)] = strings.TrimSpace(r)
+		}
+
+		// both sides are collected at the same time, so they are as close in time as possible
+		var left, right *models.Snapshot
+		var leftErr, rightErr error
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() { defer wg.Done(); left, leftErr = collectConfig(*leftCfg, cliRules) }()
+		go func() { defer wg.Done(); right, rightErr = collectConfig(*rightCfg, cliRules) }()
+		wg.Wait()
+		if leftErr != nil {
+			panic(fmt.Errorf("compare: left: %w", leftErr))
+		}
+		if rightErr != nil {
+			panic(fmt.Errorf("compare: right: %w", rightErr))
+		}
+
+		if len(mapping.Databases) == 0 && mapping.PairSingle(left, right) {
+			for l, r := range mapping.Databases {
+				fmt.Printf("ℹ️ Comparing database %s (left) with %s (right)\n", l, r)
+			}
+		}
+		if err := mapping.Apply(left); err != nil {
+			panic(err)
+		}
+		opts := diffOptions()
+		printDiff(*reportOut, *format, snapshot.Compare(left, right, opts), opts)
+		return
+
+	case "three-way":
+		if *leftCfg != "" || *rightCfg != "" {
+			panic("three-way: --left and --right are compare configs; give the snapshots as --ours and --theirs")
+		}
+		if *baseSnapPath == "" || *oursSnapPath == "" || *theirsSnapPath == "" {
+			panic("three-way: --base, --ours and --theirs snapshots are required")
+		}
+		var snaps []*models.Snapshot
+		for _, path := range []string{*baseSnapPath, *oursSnapPath, *theirsSnapPath} {
+			snap, err := snapshot.LoadSnapshot(path)
+			if err != nil {
+				panic(err)
+			}
+			filters.Apply(snap)
+			snaps = append(snaps, snap)
+		}
+		snapshot.FprintThreeWay(os.Stdout, snapshot.ThreeWay(snaps[0], snaps[1], snaps[2], diffOptions()))
+		return
+
+	case "impact":
+		// structs, not changes: suppressed drift still changes the generated code
+		oldSnap, newSnap := loadPair()
+		im := generator.CompareAPI(oldSnap, newSnap, genOpts)
+		write := func(w io.Writer) error { im.Fprint(w); return nil }
+		switch *format {
+		case "", "text":
+		case "json":
+			write = im.JSON
+		default:
+			panic(fmt.Sprintf("impact: unknown --format %q", *format))
+		}
+		if err := writeOutput(*reportOut, write); err != nil {
+			panic(err)
+		}
+		return
+
+	case "usages":
+		oldSnap, newSnap := loadPair()
+		opts := diffOptions()
+		changes, _, _ := snapshot.Suppress(snapshot.Compare(oldSnap, newSnap, opts), opts.Suppressions, time.Now())
+		findings, skipped, err := codescan.Scan(*codeDir, changes, generator.CompareAPI(oldSnap, newSnap, genOpts))
 		if err != nil {
 			panic(err)
 		}
-		snapshot.Diff(oldSnap, newSnap)
+		for _, msg := range skipped {
+			fmt.Fprintln(os.Stderr, "⚠️ skipped", msg)
+		}
+		write := func(w io.Writer) error {
+			if len(findings) == 0 {
+				fmt.Fprintln(w, "✅ No affected code found in", *codeDir)
+			}
+			for _, f := range findings {
+				fmt.Fprintln(w, f)
+			}
+			return nil
+		}
+		switch *format {
+		case "", "text":
+		case "json":
+			write = func(w io.Writer) error {
+				enc := json.NewEncoder(w)
+				enc.SetIndent("", "  ")
+				return enc.Encode(findings)
+			}
+		default:
+			panic(fmt.Sprintf("usages: unknown --format %q", *format))
+		}
+		if err := writeOutput(*reportOut, write); err != nil {
+			panic(err)
+		}
+		return
+
+	case "matrix":
+		var envs []report.Env
+		for _, item := range splitList(*snapshotList) {
+			name, path, named := strings.Cut(item, "=")
+			if !named {
+				path = item
+			}
+			snap, err := snapshot.LoadSnapshot(path)
+			if err != nil {
+				panic(err)
+			}
+			filters.Apply(snap)
+			if !named {
+				name = snap.Env
+			}
+			if name == "" {
+				name = path
+			}
+			envs = append(envs, report.Env{Name: name, Snap: snap})
+		}
+		if len(envs) < 2 {
+			panic("matrix: --snapshots needs at least two snapshots")
+		}
+		base := *baseline
+		if base == "" {
+			base = envs[len(envs)-1].Name
+		}
+		m, err := report.BuildMatrix(envs, base)
+		if err != nil {
+			panic(err)
+		}
+		render := m.Markdown
+		switch *format {
+		case "", "markdown":
+		case "html":
+			render = m.HTML
+		case "json":
+			render = m.JSON
+		default:
+			panic(fmt.Sprintf("matrix: unknown --format %q", *format))
+		}
+		if err := writeOutput(*reportOut, render); err != nil {
+			panic(err)
+		}
+		if *reportOut != "" {
+			fmt.Println("✅ Matrix written:", *reportOut)
+		}
 		return
 
 	case "generate":
@@ -59,12 +474,92 @@ func main() {
 		if err != nil {
 			panic(err)
 		}
-		if err := generator.GenerateStructs(snap, *outDir); err != nil {
+		filters.Apply(snap)
+		if err := generator.GenerateStructs(snap, *outDir, genOpts); err != nil {
 			panic(err)
 		}
+		for _, c := range generator.Collisions(snap, genOpts) {
+			fmt.Println("⚠️ Name collision resolved:", c)
+		}
 		fmt.Println("✅ Structs generated into:", *outDir)
 		return
 	}
 
 	fmt.Println("Unknown mode:", *mode)
 }
+
+// printDiff writes changes in the given format to path, or stdout when path is empty.
+func printDiff(path, format string, changes []snapshot.Change, opts snapshot.DiffOptions) {
+	var write func(io.Writer) error
+	switch format {
+	case "", "text":
+		write = func(w io.Writer) error { snapshot.Fprint(w, changes, opts); return nil }
+	case "json":
+		write = func(w io.Writer) error { return snapshot.FprintJSON(w, changes, opts) }
+	case "markdown":
+		write = func(w io.Writer) error { snapshot.FprintMarkdown(w, changes, opts); return nil }
+	default:
+		panic(fmt.Sprintf("diff: unknown --format %q", format))
+	}
+	if err := writeOutput(path, write); err != nil {
+		panic(err)
+	}
+	if path != "" {
+		fmt.Println("✅ Diff written:", path)
+	}
+}
+
+// collectConfig snapshots the databases of the config at path, filtered by its rules plus rules.
+func collectConfig(path string, rules filter.Rules) (*models.Snapshot, error) {
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	filters, err := cfg.FilterSet(rules)
+	if err != nil {
+		return nil, err
+	}
+	dbs := make(map[string]string)
+	for _, db := range cfg.Databases {
+		dbs[db.Name] = db.BuildDSN()
+	}
+	return collector.CollectSnapshot(cfg.Env, dbs, collector.Options{Filters: filters})
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// loadSuppressions reads the --ignore file, or the default one when it exists.
+func loadSuppressions(path string) ([]snapshot.Suppression, error) {
+	if path == "" {
+		if _, err := os.Stat(snapshot.DefaultSuppressionsFile); err != nil {
+			return nil, nil
+		}
+		path = snapshot.DefaultSuppressionsFile
+	}
+	return snapshot.LoadSuppressions(path)
+}
+
+// writeOutput calls write with the named file, or stdout when path is empty.
+func writeOutput(path string, write func(io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,340 @@
+// Package codescan finds the Go code a schema diff affects: uses of generated struct fields,
+// `db:"..."` struct tags and SQL string literals that mention changed tables and columns.
+//
+// It parses files with go/parser and matches names syntactically, without type-checking,
+// so it works on any module without building it. Matches that can't be tied to a struct
+// or table are reported at a lower severity.
+package codescan
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Saba101/GoMetaSync/internal/generator"
+	"github.com/Saba101/GoMetaSync/internal/snapshot"
+)
+
+// Finding is one place in the code affected by a change.
+type Finding struct {
+	File     string            `json:"file"`
+	Line     int               `json:"line"`
+	Severity snapshot.Severity `json:"severity"`
+	Kind     string            `json:"kind"` // "struct", "field", "tag" or "sql"
+	Object   string            `json:"object"`
+	Message  string            `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: [%s] %s", f.File, f.Line, f.Severity, f.Message)
+}
+
+// target is a table or column a change makes unusable or different.
+type target struct {
+	path     string // dotted path in the old snapshot
+	table    string // table name
+	column   string // empty for tables
+	severity snapshot.Severity
+	why      string
+}
+
+// Scan walks the Go files under root (skipping vendor, testdata, hidden directories and generated
+// files) and reports where they use what changes and im touch. Findings are sorted by file and line.
+// Files that don't parse are skipped and listed, with their errors, in skipped.
+func Scan(root string, changes []snapshot.Change, im generator.Impact) (findings []Finding, skipped []string, err error) {
+	targets := targetsOf(changes)
+	s := &scanner{fset: token.NewFileSet(), targets: targets, impact: im}
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		return s.file(path)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	slices.SortFunc(s.findings, func(a, b Finding) int {
+		if c := strings.Compare(a.File, b.File); c != 0 {
+			return c
+		}
+		return a.Line - b.Line
+	})
+	return s.findings, s.skipped, nil
+}
+
+// targetsOf lists the dropped, renamed and retyped tables and columns of changes.
+func targetsOf(changes []snapshot.Change) []target {
+	var out []target
+	for _, c := range changes {
+		if c.Object != snapshot.ObjectTable && c.Object != snapshot.ObjectColumn {
+			continue
+		}
+		t := target{table: c.Table, severity: c.Severity()}
+		if c.Object == snapshot.ObjectColumn {
+			t.column = c.Name
+		}
+		switch c.Action {
+		case snapshot.Dropped:
+			t.why = fmt.Sprintf("%s dropped", c.Object)
+		case snapshot.Renamed:
+			t.severity = snapshot.SeverityHigh
+			if c.Object == snapshot.ObjectTable {
+				t.table = c.OldName
+			} else {
+				t.column = c.OldName
+			}
+			t.why = fmt.Sprintf("%s renamed to %s", c.Object, nameOf(c))
+		case snapshot.Changed:
+			if c.Object != snapshot.ObjectColumn {
+				continue
+			}
+			t.why = fmt.Sprintf("type changed %v → %v", c.Old, c.New)
+			if c.TypeChange != nil {
+				t.why += " (" + string(c.TypeChange.Class) + ")"
+			}
+		default:
+			continue
+		}
+		t.path = c.DB + "." + c.Schema + "." + t.table
+		if t.column != "" {
+			t.path += "." + t.column
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+func nameOf(c snapshot.Change) string {
+	if c.Object == snapshot.ObjectTable {
+		return c.Table
+	}
+	return c.Name
+}
+
+type scanner struct {
+	fset     *token.FileSet
+	targets  []target
+	impact   generator.Impact
+	findings []Finding
+	skipped  []string
+}
+
+func (s *scanner) file(path string) error {
+	f, err := parser.ParseFile(s.fset, path, nil, parser.ParseComments)
+	if err != nil {
+		// unparsable files can't be analyzed; don't fail the whole scan
+		s.skipped = append(s.skipped, err.Error())
+		return nil
+	}
+	if ast.IsGenerated(f) {
+		return nil
+	}
+
+	// identifiers the file uses, to tell field matches on a known struct from name-only ones
+	idents := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Ident:
+			idents[n.Name] = true
+		case *ast.SelectorExpr:
+			idents[n.Sel.Name] = true
+		}
+		return true
+	})
+
+	add := func(pos token.Pos, lineOffset int, sev snapshot.Severity, kind, object, msg string) {
+		s.findings = append(s.findings, Finding{
+			File: path, Line: s.fset.Position(pos).Line + lineOffset,
+			Severity: sev, Kind: kind, Object: object, Message: msg,
+		})
+	}
+
+	tagged := map[*ast.StructType]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Ident:
+			for _, st := range s.impact.StructsRemoved {
+				if n.Name == st.Struct && n.Obj == nil {
+					add(n.Pos(), 0, snapshot.SeverityHigh, "struct", st.Table, fmt.Sprintf("uses struct %s, removed (%s)", st.Struct, st.Table))
+				}
+			}
+
+		case *ast.SelectorExpr:
+			s.fieldUse(n.Sel, idents, add)
+
+		case *ast.KeyValueExpr:
+			// composite literal keys: Users{SecretKey: ...}
+			if key, ok := n.Key.(*ast.Ident); ok {
+				s.fieldUse(key, idents, add)
+			}
+
+		case *ast.TypeSpec:
+			if st, ok := n.Type.(*ast.StructType); ok {
+				s.tags(n.Name.Name, st, add)
+				tagged[st] = true
+			}
+
+		case *ast.StructType:
+			// anonymous structs; named ones are handled with their TypeSpec
+			if !tagged[n] {
+				s.tags("", n, add)
+			}
+
+		case *ast.BasicLit:
+			if n.Kind == token.STRING {
+				s.sql(n, add)
+			}
+		}
+		return true
+	})
+	return nil
+}
+
+type addFunc func(pos token.Pos, lineOffset int, sev snapshot.Severity, kind, object, msg string)
+
+// fieldUse reports a removed or retyped field. Fields named like one of a struct the file doesn't
+// mention are reported one severity lower: the name may belong to another type.
+func (s *scanner) fieldUse(sel *ast.Ident, idents map[string]bool, add addFunc) {
+	report := func(fc generator.FieldChange, sev snapshot.Severity, what string) {
+		if sel.Name != fc.Field {
+			return
+		}
+		msg := fmt.Sprintf("uses field %s.%s, %s (%s)", fc.Struct, fc.Field, what, fc.Column)
+		if !idents[fc.Struct] {
+			sev = lower(sev)
+			msg += "; matched by name only"
+		}
+		add(sel.Pos(), 0, sev, "field", fc.Column, msg)
+	}
+	for _, fc := range s.impact.FieldsRemoved {
+		report(fc, snapshot.SeverityHigh, "removed")
+	}
+	for _, fc := range s.impact.FieldsRetyped {
+		report(fc, snapshot.SeverityMedium, fmt.Sprintf("retyped %s → %s", fc.OldType, fc.NewType))
+	}
+}
+
+// tags reports `db:"column"` tags of struct name naming a changed column. Tags in structs not named
+// after the column's table (anonymous ones included) are reported one severity lower: columns like
+// id or created_at are in most tables.
+func (s *scanner) tags(name string, st *ast.StructType, add addFunc) {
+	for _, field := range st.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		raw, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			continue
+		}
+		col, _, _ := strings.Cut(reflect.StructTag(raw).Get("db"), ",")
+		if col == "" {
+			continue
+		}
+		for _, t := range s.targets {
+			if t.column != col {
+				continue
+			}
+			sev, msg := t.severity, fmt.Sprintf("db tag %q: column %s %s", col, t.path, t.why)
+			if !namedAfter(name, t.table) {
+				sev = lower(sev)
+				msg += "; matched by name only"
+			}
+			add(field.Tag.Pos(), 0, sev, "tag", t.path, msg)
+		}
+	}
+}
+
+// namedAfter reports whether a struct name looks like it maps the table: users → User, Users,
+// BillingUser; order_items → OrderItem.
+func namedAfter(name, table string) bool {
+	if name == "" {
+		return false
+	}
+	alnum := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, s)
+	}
+	n, t := alnum(name), alnum(table)
+	forms := []string{t, strings.TrimSuffix(t, "s"), strings.TrimSuffix(t, "es")}
+	if base, ok := strings.CutSuffix(t, "ies"); ok {
+		forms = append(forms, base+"y")
+	}
+	for _, form := range forms {
+		if form != "" && strings.HasSuffix(n, form) {
+			return true
+		}
+	}
+	return false
+}
+
+var sqlRe = regexp.MustCompile(`(?i)\b(select|insert\s+into|update|delete\s+from|alter\s+table|join|from)\b`)
+
+// sql reports SQL string literals that mention a changed table, or a changed column together with its table.
+func (s *scanner) sql(lit *ast.BasicLit, add addFunc) {
+	text, err := strconv.Unquote(lit.Value)
+	if err != nil || !sqlRe.MatchString(text) {
+		return
+	}
+	for _, t := range s.targets {
+		at := wordIndex(text, t.table)
+		if at < 0 {
+			continue
+		}
+		what := "table " + t.path
+		if t.column != "" {
+			if at = wordIndex(text, t.column); at < 0 {
+				continue
+			}
+			what = "column " + t.path
+		}
+		// point at the line of the match inside multi-line literals
+		line := strings.Count(lit.Value[:min(len(lit.Value), at+1)], "\n")
+		add(lit.Pos(), line, t.severity, "sql", t.path, fmt.Sprintf("SQL mentions %s, %s", what, t.why))
+	}
+}
+
+// wordIndex finds name as a whole identifier in text, case-insensitively, or returns -1.
+func wordIndex(text, name string) int {
+	re, err := regexp.Compile(`(?i)(^|[^\w$])"?` + regexp.QuoteMeta(name) + `"?($|[^\w$])`)
+	if err != nil {
+		return -1
+	}
+	loc := re.FindStringIndex(text)
+	if loc == nil {
+		return -1
+	}
+	return loc[0]
+}
+
+func lower(s snapshot.Severity) snapshot.Severity {
+	switch s {
+	case snapshot.SeverityHigh:
+		return snapshot.SeverityMedium
+	}
+	return snapshot.SeverityLow
+}
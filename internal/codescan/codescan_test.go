@@ -0,0 +1,110 @@
+package codescan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/generator"
+	"github.com/Saba101/GoMetaSync/internal/snapshot"
+)
+
+const src = `package app
+
+type User struct {
+	ID    int    ` + "`db:\"id\"`" + `
+	Email string ` + "`db:\"email\"`" + `
+}
+
+type Order struct {
+	ID int ` + "`db:\"id\"`" + `
+}
+
+func load(u User) string {
+	_ = struct {
+		Email string ` + "`db:\"email\"`" + `
+	}{}
+	_ = "SELECT email FROM users"
+	_ = "SELECT email FROM accounts"
+	return u.Email
+}
+`
+
+func write(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "app.go", src)
+	write(t, dir, "broken.go", "package app\nfunc {")
+
+	changes := []snapshot.Change{
+		{Action: snapshot.Dropped, Object: snapshot.ObjectColumn, DB: "app", Schema: "public", Table: "users", Name: "email", Old: "text"},
+		{Action: snapshot.Dropped, Object: snapshot.ObjectColumn, DB: "app", Schema: "public", Table: "users", Name: "id", Old: "integer"},
+	}
+	im := generator.Impact{FieldsRemoved: []generator.FieldChange{
+		{Struct: "User", Field: "Email", Column: "app.public.users.email", OldType: "string"},
+	}}
+	findings, skipped, err := Scan(dir, changes, im)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(skipped) != 1 || !strings.Contains(skipped[0], "broken.go") {
+		t.Errorf("skipped = %v, want broken.go", skipped)
+	}
+
+	type key struct {
+		line int
+		kind string
+	}
+	got := map[key]snapshot.Severity{}
+	for _, f := range findings {
+		got[key{f.Line, f.Kind}] = f.Severity
+	}
+	want := map[key]snapshot.Severity{
+		{4, "tag"}:    snapshot.SeverityHigh,   // User.ID: struct named after users
+		{5, "tag"}:    snapshot.SeverityHigh,   // User.Email
+		{9, "tag"}:    snapshot.SeverityMedium, // Order.ID: name-only match
+		{14, "tag"}:   snapshot.SeverityMedium, // anonymous struct
+		{16, "sql"}:   snapshot.SeverityHigh,   // mentions users and email
+		{18, "field"}: snapshot.SeverityHigh,   // u.Email, file mentions User
+	}
+	for k, sev := range want {
+		if got[k] != sev {
+			t.Errorf("line %d %s: severity %q, want %q", k.line, k.kind, got[k], sev)
+		}
+	}
+	if len(findings) != len(want) {
+		t.Errorf("%d findings, want %d:", len(findings), len(want))
+		for _, f := range findings {
+			t.Log(f)
+		}
+	}
+}
+
+func TestNamedAfter(t *testing.T) {
+	tests := []struct {
+		name, table string
+		want        bool
+	}{
+		{"User", "users", true},
+		{"Users", "users", true},
+		{"BillingInvoice", "invoices", true},
+		{"OrderItem", "order_items", true},
+		{"Category", "categories", true},
+		{"Box", "boxes", true},
+		{"Order", "users", false},
+		{"", "users", false},
+	}
+	for _, tt := range tests {
+		if got := namedAfter(tt.name, tt.table); got != tt.want {
+			t.Errorf("namedAfter(%q, %q) = %v, want %v", tt.name, tt.table, got, tt.want)
+		}
+	}
+}
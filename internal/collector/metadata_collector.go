@@ -2,16 +2,26 @@ package collector
 
 import (
 	"context"
+	"fmt"
 	"regexp"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/Saba101/GoMetaSync/internal/filter"
 	"github.com/Saba101/GoMetaSync/internal/models"
 	"github.com/jackc/pgx/v5"
 )
 
-func CollectSnapshot(env string, dbs map[string]string) (*models.Snapshot, error) {
+// Options controls how CollectSnapshot reads each database.
+type Options struct {
+	// ExportSnapshot calls pg_export_snapshot() inside the collection transaction and records its id.
+	ExportSnapshot bool
+	// Filters selects the schemas, tables, columns and object types collected from each database.
+	Filters filter.Set
+}
+
+func CollectSnapshot(env string, dbs map[string]string, opts Options) (*models.Snapshot, error) {
 	snap := &models.Snapshot{
 		Timestamp: time.Now(),
 		Env:       env,
@@ -20,104 +30,175 @@ func CollectSnapshot(env string, dbs map[string]string) (*models.Snapshot, error
 	ctx := context.Background()
 
 	for dbName, dsn := range dbs {
-		conn, err := pgx.Connect(ctx, dsn)
+		dbSnap, err := collectDatabase(ctx, dbName, dsn, opts)
 		if err != nil {
 			return nil, err
 		}
-
-		dbSnap := models.DatabaseSnapshot{
-			DBName:  dbName,
-			Schemas: map[string]models.SchemaSnapshot{},
+		if problems := validateDatabase(dbSnap); len(problems) > 0 {
+			return nil, &ValidationError{DB: dbName, Problems: problems}
 		}
+		// tables and columns are pruned after validation so excluded objects can't look missing
+		opts.Filters.For(dbName).ApplyDatabase(&dbSnap)
+		snap.Databases[dbName] = dbSnap
+	}
 
-		// ---- Schemas
-		rows, err := conn.Query(ctx, `
-			SELECT schema_name
-			FROM information_schema.schemata
-			WHERE schema_name NOT IN ('pg_catalog','information_schema')
-			ORDER BY schema_name`)
-		if err != nil {
-			conn.Close(ctx)
-			return nil, err
-		}
-		for rows.Next() {
-			var schema string
-			_ = rows.Scan(&schema)
-			dbSnap.Schemas[schema] = models.SchemaSnapshot{
-				Name:   schema,
-				Tables: map[string]models.TableSnapshot{},
-			}
+	return snap, nil
+}
+
+// collectDatabase reads every catalog query for one database inside a single
+// REPEATABLE READ READ ONLY transaction, so a migration running concurrently
+// can't leave the snapshot half old and half new.
+func collectDatabase(ctx context.Context, dbName, dsn string, opts Options) (models.DatabaseSnapshot, error) {
+	dbSnap := models.DatabaseSnapshot{
+		DBName:  dbName,
+		Schemas: map[string]models.SchemaSnapshot{},
+	}
+
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return dbSnap, fmt.Errorf("collector: connect to db %q: %w", dbName, err)
+	}
+	defer conn.Close(ctx)
+
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return dbSnap, fmt.Errorf("collector: begin transaction on db %q: %w", dbName, err)
+	}
+	// read-only: nothing to commit
+	defer tx.Rollback(ctx)
+
+	// ---- Capture point
+	if err := loadCapturePoint(ctx, tx, opts, &dbSnap); err != nil {
+		return dbSnap, &QueryError{DB: dbName, Query: "capture point", Err: err}
+	}
+
+	// ---- Schemas
+	if err := loadSchemas(ctx, tx, &dbSnap); err != nil {
+		return dbSnap, &QueryError{DB: dbName, Query: "schemas", Err: err}
+	}
+	for schema := range dbSnap.Schemas {
+		if !opts.Filters.For(dbName).Schema(schema) {
+			delete(dbSnap.Schemas, schema)
 		}
-		rows.Close()
-
-		// ---- Tables & Columns
-		for schema := range dbSnap.Schemas {
-			// columns
-			colRows, err := conn.Query(ctx, `
-				SELECT table_name, column_name, data_type
-				FROM information_schema.columns
-				WHERE table_schema = $1
-				ORDER BY table_name, ordinal_position`, schema)
-			if err != nil {
-				conn.Close(ctx)
-				return nil, err
-			}
-			for colRows.Next() {
-				var table, col, dtype string
-				_ = colRows.Scan(&table, &col, &dtype)
-
-				t, ok := dbSnap.Schemas[schema].Tables[table]
-				if !ok {
-					t = models.TableSnapshot{
-						Name:              table,
-						Columns:           map[string]string{},
-						UniqueConstraints: map[string][]string{},
-						CheckConstraints:  map[string]string{},
-						ForeignKeys:       map[string]models.ForeignKey{},
-						Indexes:           map[string]models.Index{},
-					}
-				}
-				t.Columns[col] = dtype
-				dbSnap.Schemas[schema].Tables[table] = t
-			}
-			colRows.Close()
+	}
 
+	// ---- Tables & Columns
+	for schema := range dbSnap.Schemas {
+		steps := []struct {
+			query string
+			load  func(context.Context, pgx.Tx, string, *models.DatabaseSnapshot) error
+		}{
+			{"columns", loadColumns},
 			// primary keys & unique constraints (information_schema)
-			if err := loadPKs(ctx, conn, schema, &dbSnap); err != nil {
-				conn.Close(ctx)
-				return nil, err
-			}
-			if err := loadUniqueConstraints(ctx, conn, schema, &dbSnap); err != nil {
-				conn.Close(ctx)
-				return nil, err
-			}
-			if err := loadCheckConstraints(ctx, conn, schema, &dbSnap); err != nil {
-				conn.Close(ctx)
-				return nil, err
-			}
-			// foreign keys
-			if err := loadFKs(ctx, conn, schema, &dbSnap); err != nil {
-				conn.Close(ctx)
-				return nil, err
-			}
+			{"primary keys", loadPKs},
+			{"unique constraints", loadUniqueConstraints},
+			{"check constraints", loadCheckConstraints},
+			{"foreign keys", loadFKs},
 			// indexes (via pg_indexes view)
-			if err := loadIndexes(ctx, conn, schema, &dbSnap); err != nil {
-				conn.Close(ctx)
-				return nil, err
+			{"indexes", loadIndexes},
+		}
+		for _, step := range steps {
+			if err := step.load(ctx, tx, schema, &dbSnap); err != nil {
+				return dbSnap, &QueryError{DB: dbName, Schema: schema, Query: step.query, Err: err}
 			}
 		}
-
-		snap.Databases[dbName] = dbSnap
-		conn.Close(ctx)
 	}
 
-	return snap, nil
+	return dbSnap, nil
 }
 
 // ---------- helpers ----------
 
-func loadPKs(ctx context.Context, conn *pgx.Conn, schema string, dbSnap *models.DatabaseSnapshot) error {
-	rows, err := conn.Query(ctx, `
+// loadCapturePoint records where in the server's history the transaction snapshot was taken.
+// Must run first: the repeatable-read snapshot is fixed by the first query in the transaction.
+func loadCapturePoint(ctx context.Context, tx pgx.Tx, opts Options, dbSnap *models.DatabaseSnapshot) error {
+	err := tx.QueryRow(ctx, `
+		SELECT
+		  txid_current_snapshot()::text,
+		  -- a standby that hasn't replayed any WAL yet has no replay LSN
+		  COALESCE(CASE WHEN pg_is_in_recovery() THEN pg_last_wal_replay_lsn() ELSE pg_current_wal_lsn() END::text, '')`,
+	).Scan(&dbSnap.TxSnapshot, &dbSnap.LSN)
+	if err != nil { return err }
+
+	if opts.ExportSnapshot {
+		if err := tx.QueryRow(ctx, `SELECT pg_export_snapshot()`).Scan(&dbSnap.ExportedSnapshot); err != nil { return err }
+	}
+	return nil
+}
+
+func loadSchemas(ctx context.Context, tx pgx.Tx, dbSnap *models.DatabaseSnapshot) error {
+	rows, err := tx.Query(ctx, `
+		SELECT schema_name
+		FROM information_schema.schemata
+		WHERE schema_name NOT IN ('pg_catalog','information_schema')
+		  -- schemas created by extensions (CREATE EXTENSION ... SCHEMA) aren't ours to track
+		  AND NOT EXISTS (
+		    SELECT 1
+		    FROM pg_catalog.pg_namespace n
+		    JOIN pg_catalog.pg_depend d
+		      ON d.classid = 'pg_catalog.pg_namespace'::regclass
+		     AND d.objid   = n.oid
+		     AND d.deptype = 'e'
+		    WHERE n.nspname = schema_name)
+		ORDER BY schema_name`)
+	if err != nil { return err }
+
+	for rows.Next() {
+		var schema string
+		if err := rows.Scan(&schema); err != nil { rows.Close(); return err }
+		dbSnap.Schemas[schema] = models.SchemaSnapshot{
+			Name:   schema,
+			Tables: map[string]models.TableSnapshot{},
+		}
+	}
+	rows.Close()
+	return rows.Err()
+}
+
+func loadColumns(ctx context.Context, tx pgx.Tx, schema string, dbSnap *models.DatabaseSnapshot) error {
+	rows, err := tx.Query(ctx, `
+		SELECT c.table_name, c.column_name, c.data_type, format_type(a.atttypid, a.atttypmod), a.attnum, t.table_type
+		FROM information_schema.columns c
+		JOIN information_schema.tables t
+		  ON t.table_schema = c.table_schema
+		 AND t.table_name   = c.table_name
+		JOIN pg_catalog.pg_attribute a
+		  ON a.attrelid = format('%I.%I', c.table_schema, c.table_name)::regclass
+		 AND a.attname  = c.column_name
+		WHERE c.table_schema = $1
+		ORDER BY c.table_name, c.ordinal_position`, schema)
+	if err != nil { return err }
+
+	for rows.Next() {
+		var table, col, dtype, fullType, tableType string
+		var pos int16
+		if err := rows.Scan(&table, &col, &dtype, &fullType, &pos, &tableType); err != nil { rows.Close(); return err }
+
+		t, ok := dbSnap.Schemas[schema].Tables[table]
+		if !ok {
+			t = models.TableSnapshot{
+				Name:              table,
+				View:              tableType == "VIEW",
+				Columns:           map[string]string{},
+				ColumnTypes:       map[string]string{},
+				ColumnPositions:   map[string]int{},
+				UniqueConstraints: map[string][]string{},
+				CheckConstraints:  map[string]string{},
+				ForeignKeys:       map[string]models.ForeignKey{},
+				Indexes:           map[string]models.Index{},
+			}
+		}
+		t.Columns[col] = dtype
+		t.ColumnTypes[col] = fullType
+		t.ColumnPositions[col] = int(pos)
+		dbSnap.Schemas[schema].Tables[table] = t
+	}
+	rows.Close()
+	return rows.Err()
+}
+
+func loadPKs(ctx context.Context, tx pgx.Tx, schema string, dbSnap *models.DatabaseSnapshot) error {
+	rows, err := tx.Query(ctx, `
 		SELECT tc.table_name, kcu.column_name, kcu.ordinal_position
 		FROM information_schema.table_constraints tc
 		JOIN information_schema.key_column_usage kcu
@@ -132,10 +213,11 @@ func loadPKs(ctx context.Context, conn *pgx.Conn, schema string, dbSnap *models.
 	var es []entry
 	for rows.Next() {
 		var e entry
-		_ = rows.Scan(&e.table, &e.col, &e.pos)
+		if err := rows.Scan(&e.table, &e.col, &e.pos); err != nil { rows.Close(); return err }
 		es = append(es, e)
 	}
 	rows.Close()
+	if err := rows.Err(); err != nil { return err }
 
 	// assemble ordered list
 	m := map[string][]struct{ pos int32; col string }{}
@@ -153,8 +235,8 @@ func loadPKs(ctx context.Context, conn *pgx.Conn, schema string, dbSnap *models.
 	return nil
 }
 
-func loadUniqueConstraints(ctx context.Context, conn *pgx.Conn, schema string, dbSnap *models.DatabaseSnapshot) error {
-	rows, err := conn.Query(ctx, `
+func loadUniqueConstraints(ctx context.Context, tx pgx.Tx, schema string, dbSnap *models.DatabaseSnapshot) error {
+	rows, err := tx.Query(ctx, `
 		SELECT tc.table_name, tc.constraint_name, kcu.column_name, kcu.ordinal_position
 		FROM information_schema.table_constraints tc
 		JOIN information_schema.key_column_usage kcu
@@ -169,10 +251,11 @@ func loadUniqueConstraints(ctx context.Context, conn *pgx.Conn, schema string, d
 	var es []entry
 	for rows.Next() {
 		var e entry
-		_ = rows.Scan(&e.table, &e.cname, &e.col, &e.pos)
+		if err := rows.Scan(&e.table, &e.cname, &e.col, &e.pos); err != nil { rows.Close(); return err }
 		es = append(es, e)
 	}
 	rows.Close()
+	if err := rows.Err(); err != nil { return err }
 
 	group := map[string]map[string][]struct{ pos int32; col string }{} // table -> cname -> list
 	for _, e := range es {
@@ -193,8 +276,8 @@ func loadUniqueConstraints(ctx context.Context, conn *pgx.Conn, schema string, d
 	return nil
 }
 
-func loadCheckConstraints(ctx context.Context, conn *pgx.Conn, schema string, dbSnap *models.DatabaseSnapshot) error {
-	rows, err := conn.Query(ctx, `
+func loadCheckConstraints(ctx context.Context, tx pgx.Tx, schema string, dbSnap *models.DatabaseSnapshot) error {
+	rows, err := tx.Query(ctx, `
 		SELECT tc.table_name, tc.constraint_name, cc.check_clause
 		FROM information_schema.table_constraints tc
 		JOIN information_schema.check_constraints cc
@@ -206,25 +289,25 @@ func loadCheckConstraints(ctx context.Context, conn *pgx.Conn, schema string, db
 
 	for rows.Next() {
 		var tbl, cname, clause string
-		_ = rows.Scan(&tbl, &cname, &clause)
+		if err := rows.Scan(&tbl, &cname, &clause); err != nil { rows.Close(); return err }
 		t := dbSnap.Schemas[schema].Tables[tbl]
 		if t.CheckConstraints == nil { t.CheckConstraints = map[string]string{} }
 		t.CheckConstraints[cname] = clause
 		dbSnap.Schemas[schema].Tables[tbl] = t
 	}
 	rows.Close()
-	return nil
+	return rows.Err()
 }
 
-func loadFKs(ctx context.Context, conn *pgx.Conn, schema string, dbSnap *models.DatabaseSnapshot) error {
-	rows, err := conn.Query(ctx, `
+func loadFKs(ctx context.Context, tx pgx.Tx, schema string, dbSnap *models.DatabaseSnapshot) error {
+	rows, err := tx.Query(ctx, `
 		SELECT
 		  tc.table_name,
 		  tc.constraint_name,
 		  kcu.column_name,
-		  ccu.table_schema  AS ref_schema,
-		  ccu.table_name    AS ref_table,
-		  ccu.column_name   AS ref_column,
+		  rcu.table_schema  AS ref_schema,
+		  rcu.table_name    AS ref_table,
+		  rcu.column_name   AS ref_column,
 		  kcu.ordinal_position,
 		  rc.update_rule,
 		  rc.delete_rule
@@ -235,9 +318,11 @@ func loadFKs(ctx context.Context, conn *pgx.Conn, schema string, dbSnap *models.
 		JOIN information_schema.referential_constraints rc
 		  ON tc.constraint_name = rc.constraint_name
 		 AND tc.constraint_schema = rc.constraint_schema
-		JOIN information_schema.constraint_column_usage ccu
-		  ON rc.unique_constraint_name = ccu.constraint_name
-		 AND rc.unique_constraint_schema = ccu.constraint_schema
+		-- pair each local column with its referenced column by position
+		JOIN information_schema.key_column_usage rcu
+		  ON rc.unique_constraint_name   = rcu.constraint_name
+		 AND rc.unique_constraint_schema = rcu.constraint_schema
+		 AND kcu.position_in_unique_constraint = rcu.ordinal_position
 		WHERE tc.table_schema = $1 AND tc.constraint_type = 'FOREIGN KEY'
 		ORDER BY tc.table_name, tc.constraint_name, kcu.ordinal_position`, schema)
 	if err != nil { return err }
@@ -249,10 +334,11 @@ func loadFKs(ctx context.Context, conn *pgx.Conn, schema string, dbSnap *models.
 	var rs []row
 	for rows.Next() {
 		var r row
-		_ = rows.Scan(&r.table, &r.cname, &r.col, &r.refSchema, &r.refTable, &r.refCol, &r.pos, &r.updateRule, &r.deleteRule)
+		if err := rows.Scan(&r.table, &r.cname, &r.col, &r.refSchema, &r.refTable, &r.refCol, &r.pos, &r.updateRule, &r.deleteRule); err != nil { rows.Close(); return err }
 		rs = append(rs, r)
 	}
 	rows.Close()
+	if err := rows.Err(); err != nil { return err }
 
 	type pair struct{ pos int32; col string }
 	type refpair struct{ pos int32; col string }
@@ -307,17 +393,21 @@ func loadFKs(ctx context.Context, conn *pgx.Conn, schema string, dbSnap *models.
 	return nil
 }
 
-func loadIndexes(ctx context.Context, conn *pgx.Conn, schema string, dbSnap *models.DatabaseSnapshot) error {
-	rows, err := conn.Query(ctx, `
+func loadIndexes(ctx context.Context, tx pgx.Tx, schema string, dbSnap *models.DatabaseSnapshot) error {
+	rows, err := tx.Query(ctx, `
 		SELECT tablename, indexname, indexdef
-		FROM pg_catalog.pg_indexes
+		FROM pg_catalog.pg_indexes i
 		WHERE schemaname = $1
+		  -- materialized views have indexes but no information_schema columns
+		  AND NOT EXISTS (
+		    SELECT 1 FROM pg_catalog.pg_matviews mv
+		    WHERE mv.schemaname = i.schemaname AND mv.matviewname = i.tablename)
 		ORDER BY tablename, indexname`, schema)
 	if err != nil { return err }
 
 	for rows.Next() {
 		var tbl, name, def string
-		_ = rows.Scan(&tbl, &name, &def)
+		if err := rows.Scan(&tbl, &name, &def); err != nil { rows.Close(); return err }
 		t := dbSnap.Schemas[schema].Tables[tbl]
 		if t.Indexes == nil { t.Indexes = map[string]models.Index{} }
 		idx := models.Index{
@@ -330,7 +420,7 @@ func loadIndexes(ctx context.Context, conn *pgx.Conn, schema string, dbSnap *mod
 		dbSnap.Schemas[schema].Tables[tbl] = t
 	}
 	rows.Close()
-	return nil
+	return rows.Err()
 }
 
 // crude but reliable parser for column list in pg_indexes.indexdef
@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+)
+
+// QueryError is returned when a catalog query fails or its rows can't be read.
+type QueryError struct {
+	DB     string
+	Schema string // empty for database-level queries
+	Query  string
+	Err    error
+}
+
+func (e *QueryError) Error() string {
+	if e.Schema == "" {
+		return fmt.Sprintf("collector: db %q: %s query: %v", e.DB, e.Query, e.Err)
+	}
+	return fmt.Sprintf("collector: db %q schema %q: %s query: %v", e.DB, e.Schema, e.Query, e.Err)
+}
+
+func (e *QueryError) Unwrap() error { return e.Err }
+
+// ValidationError is returned when a collected database is structurally impossible,
+// which usually means a catalog query silently returned partial results (e.g. missing privileges).
+type ValidationError struct {
+	DB       string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("collector: db %q: snapshot failed validation:\n  - %s", e.DB, strings.Join(e.Problems, "\n  - "))
+}
+
+// validateDatabase checks that every constraint refers to columns and tables that were collected.
+// Index columns are not checked: they are best-effort parsed and may be expressions.
+func validateDatabase(db models.DatabaseSnapshot) []string {
+	var problems []string
+	for schemaName, schema := range db.Schemas {
+		for tblName, t := range schema.Tables {
+			where := fmt.Sprintf("%s.%s", schemaName, tblName)
+
+			if len(t.Columns) == 0 {
+				problems = append(problems, fmt.Sprintf("%s: table has constraints or indexes but no columns", where))
+				continue
+			}
+			for _, c := range t.PrimaryKey {
+				if _, ok := t.Columns[c]; !ok {
+					problems = append(problems, fmt.Sprintf("%s: primary key column %q not in columns", where, c))
+				}
+			}
+			if hasDuplicates(t.PrimaryKey) {
+				problems = append(problems, fmt.Sprintf("%s: primary key lists a column twice %v", where, t.PrimaryKey))
+			}
+			for name, cols := range t.UniqueConstraints {
+				if hasDuplicates(cols) {
+					problems = append(problems, fmt.Sprintf("%s: unique constraint %s lists a column twice %v", where, name, cols))
+				}
+				for _, c := range cols {
+					if _, ok := t.Columns[c]; !ok {
+						problems = append(problems, fmt.Sprintf("%s: unique constraint %s column %q not in columns", where, name, c))
+					}
+				}
+			}
+			for name, fk := range t.ForeignKeys {
+				for _, c := range fk.Columns {
+					if _, ok := t.Columns[c]; !ok {
+						problems = append(problems, fmt.Sprintf("%s: foreign key %s column %q not in columns", where, name, c))
+					}
+				}
+				if hasDuplicates(fk.Columns) {
+					problems = append(problems, fmt.Sprintf("%s: foreign key %s lists a column twice %v", where, name, fk.Columns))
+				}
+				if len(fk.Columns) != len(fk.RefColumns) {
+					problems = append(problems, fmt.Sprintf("%s: foreign key %s has %d columns but %d referenced columns",
+						where, name, len(fk.Columns), len(fk.RefColumns)))
+				}
+
+				// the referenced table is only checked when its schema was collected too
+				refSchema, ok := db.Schemas[fk.RefSchema]
+				if !ok {
+					continue
+				}
+				ref, ok := refSchema.Tables[fk.RefTable]
+				if !ok {
+					problems = append(problems, fmt.Sprintf("%s: foreign key %s references missing table %s.%s",
+						where, name, fk.RefSchema, fk.RefTable))
+					continue
+				}
+				for _, c := range fk.RefColumns {
+					if _, ok := ref.Columns[c]; !ok {
+						problems = append(problems, fmt.Sprintf("%s: foreign key %s references missing column %s.%s.%s",
+							where, name, fk.RefSchema, fk.RefTable, c))
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func hasDuplicates(cols []string) bool {
+	seen := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		if seen[c] {
+			return true
+		}
+		seen[c] = true
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+)
+
+func TestValidateDatabase(t *testing.T) {
+	users := func() models.TableSnapshot {
+		return models.TableSnapshot{
+			Name:       "users",
+			Columns:    map[string]string{"id": "integer", "email": "text"},
+			PrimaryKey: []string{"id"},
+		}
+	}
+	orders := func() models.TableSnapshot {
+		return models.TableSnapshot{
+			Name:    "orders",
+			Columns: map[string]string{"id": "integer", "user_id": "integer"},
+			ForeignKeys: map[string]models.ForeignKey{"orders_user_id_fkey": {
+				Name: "orders_user_id_fkey", Columns: []string{"user_id"},
+				RefSchema: "public", RefTable: "users", RefColumns: []string{"id"},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(u, o *models.TableSnapshot)
+		want   string // substring of the only problem, "" for none
+	}{
+		{"valid", func(u, o *models.TableSnapshot) {}, ""},
+		{"no columns", func(u, o *models.TableSnapshot) { u.Columns = nil; o.ForeignKeys = nil }, "public.users: table has constraints or indexes but no columns"},
+		{"pk column missing", func(u, o *models.TableSnapshot) { u.PrimaryKey = []string{"uid"} }, `primary key column "uid" not in columns`},
+		{"pk duplicate", func(u, o *models.TableSnapshot) { u.PrimaryKey = []string{"id", "id"} }, "primary key lists a column twice"},
+		{"unique column missing", func(u, o *models.TableSnapshot) {
+			u.UniqueConstraints = map[string][]string{"users_nick_key": {"nick"}}
+		}, `unique constraint users_nick_key column "nick" not in columns`},
+		{"fk arity", func(u, o *models.TableSnapshot) {
+			fk := o.ForeignKeys["orders_user_id_fkey"]
+			fk.RefColumns = []string{"id", "email"}
+			o.ForeignKeys["orders_user_id_fkey"] = fk
+		}, "has 1 columns but 2 referenced columns"},
+		{"fk missing ref column", func(u, o *models.TableSnapshot) { delete(u.Columns, "id"); u.PrimaryKey = nil },
+			"references missing column public.users.id"},
+		{"fk missing ref table", func(u, o *models.TableSnapshot) { u.Name = "" },
+			"references missing table public.users"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, o := users(), orders()
+			tt.modify(&u, &o)
+			tables := map[string]models.TableSnapshot{"orders": o}
+			if u.Name != "" {
+				tables["users"] = u
+			}
+			db := models.DatabaseSnapshot{Schemas: map[string]models.SchemaSnapshot{"public": {Name: "public", Tables: tables}}}
+
+			problems := validateDatabase(db)
+			switch {
+			case tt.want == "" && len(problems) > 0:
+				t.Errorf("problems = %q, want none", problems)
+			case tt.want != "" && (len(problems) != 1 || !strings.Contains(problems[0], tt.want)):
+				t.Errorf("problems = %q, want one containing %q", problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDatabaseSkipsUncollectedSchemas(t *testing.T) {
+	db := models.DatabaseSnapshot{Schemas: map[string]models.SchemaSnapshot{"public": {Name: "public", Tables: map[string]models.TableSnapshot{
+		"orders": {Name: "orders", Columns: map[string]string{"user_id": "integer"}, ForeignKeys: map[string]models.ForeignKey{
+			"fk": {Name: "fk", Columns: []string{"user_id"}, RefSchema: "auth", RefTable: "users", RefColumns: []string{"id"}},
+		}},
+	}}}}
+	if problems := validateDatabase(db); len(problems) > 0 {
+		t.Errorf("problems = %q, want none for a reference into an uncollected schema", problems)
+	}
+}
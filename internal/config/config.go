@@ -5,6 +5,9 @@ import (
     "os"
 
     "gopkg.in/yaml.v3"
+
+    "github.com/Saba101/GoMetaSync/internal/filter"
+    "github.com/Saba101/GoMetaSync/internal/policy"
 )
 
 type DBConfig struct {
@@ -18,11 +21,17 @@ type DBConfig struct {
     Database           string `yaml:"database"`
     SSL                bool   `yaml:"ssl"`
     RejectUnauthorized bool   `yaml:"rejectUnauthorized"`
+
+    // Include/exclude rules for this database, applied by snapshot, diff and generate
+    Filters            filter.Rules `yaml:"filters"`
 }
 
 type Config struct {
     Env       string     `yaml:"env"`
     Databases []DBConfig `yaml:"databases"`
+
+    // What --mode apply may run against these databases
+    Apply     policy.Policy `yaml:"apply"`
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -37,6 +46,24 @@ func LoadConfig(path string) (*Config, error) {
     return &cfg, nil
 }
 
+// FilterSet compiles the per-database filter rules, each combined with the extra rules
+// (typically from CLI flags), which also apply on their own to databases not in the config.
+func (c *Config) FilterSet(extra filter.Rules) (filter.Set, error) {
+    def, err := extra.Compile()
+    if err != nil {
+        return filter.Set{}, err
+    }
+    set := filter.Set{Default: def, Databases: map[string]*filter.Filter{}}
+    for _, db := range c.Databases {
+        f, err := db.Filters.Merge(extra).Compile()
+        if err != nil {
+            return filter.Set{}, fmt.Errorf("config: database %q: %w", db.Name, err)
+        }
+        set.Databases[db.Name] = f
+    }
+    return set, nil
+}
+
 // BuildDSN creates a DSN from explicit host/password config if DSN is not provided
 func (db DBConfig) BuildDSN() string {
     if db.DSN != "" {
@@ -0,0 +1,299 @@
+package filter
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+)
+
+// Object types that can be included/excluded via IncludeObjects/ExcludeObjects.
+const (
+	ObjectPrimaryKey = "primary_key"
+	ObjectUnique     = "unique"
+	ObjectCheck      = "check"
+	ObjectForeignKey = "foreign_key"
+	ObjectIndex      = "index"
+)
+
+var objectTypes = []string{ObjectPrimaryKey, ObjectUnique, ObjectCheck, ObjectForeignKey, ObjectIndex}
+
+// systemSchemas are never collected, whatever the rules say.
+var systemSchemas = []string{"pg_catalog", "information_schema", "pg_toast", "pg_temp_*", "pg_toast_temp_*"}
+
+// Rules selects what is collected, diffed and generated.
+// Patterns are globs (path.Match syntax) unless prefixed with "re:", which makes them regular expressions.
+// An empty include list includes everything; excludes are applied after includes.
+type Rules struct {
+	IncludeSchemas []string `yaml:"includeSchemas"`
+	ExcludeSchemas []string `yaml:"excludeSchemas"`
+	IncludeTables  []string `yaml:"includeTables"` // matched against "table" and "schema.table"
+	ExcludeTables  []string `yaml:"excludeTables"`
+	IncludeColumns []string `yaml:"includeColumns"` // matched against "column", "table.column" and "schema.table.column"
+	ExcludeColumns []string `yaml:"excludeColumns"`
+	IncludeObjects []string `yaml:"includeObjects"` // primary_key | unique | check | foreign_key | index
+	ExcludeObjects []string `yaml:"excludeObjects"`
+}
+
+// Merge returns the rules of r followed by those of o.
+func (r Rules) Merge(o Rules) Rules {
+	return Rules{
+		IncludeSchemas: append(append([]string{}, r.IncludeSchemas...), o.IncludeSchemas...),
+		ExcludeSchemas: append(append([]string{}, r.ExcludeSchemas...), o.ExcludeSchemas...),
+		IncludeTables:  append(append([]string{}, r.IncludeTables...), o.IncludeTables...),
+		ExcludeTables:  append(append([]string{}, r.ExcludeTables...), o.ExcludeTables...),
+		IncludeColumns: append(append([]string{}, r.IncludeColumns...), o.IncludeColumns...),
+		ExcludeColumns: append(append([]string{}, r.ExcludeColumns...), o.ExcludeColumns...),
+		IncludeObjects: append(append([]string{}, r.IncludeObjects...), o.IncludeObjects...),
+		ExcludeObjects: append(append([]string{}, r.ExcludeObjects...), o.ExcludeObjects...),
+	}
+}
+
+// Filter is a compiled set of Rules. A nil *Filter only excludes system schemas.
+type Filter struct {
+	includeSchemas, excludeSchemas patterns
+	includeTables, excludeTables   patterns
+	includeColumns, excludeColumns patterns
+	includeObjects, excludeObjects []string
+}
+
+// Compile validates every pattern and object type in r.
+func (r Rules) Compile() (*Filter, error) {
+	var f Filter
+	var err error
+	compile := func(dst *patterns, src []string) {
+		if err == nil {
+			*dst, err = compilePatterns(src)
+		}
+	}
+	compile(&f.includeSchemas, r.IncludeSchemas)
+	compile(&f.excludeSchemas, append(append([]string{}, systemSchemas...), r.ExcludeSchemas...))
+	compile(&f.includeTables, r.IncludeTables)
+	compile(&f.excludeTables, r.ExcludeTables)
+	compile(&f.includeColumns, r.IncludeColumns)
+	compile(&f.excludeColumns, r.ExcludeColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, list := range [][]string{r.IncludeObjects, r.ExcludeObjects} {
+		for _, o := range list {
+			if !slices.Contains(objectTypes, o) {
+				return nil, fmt.Errorf("filter: unknown object type %q (want one of %s)", o, strings.Join(objectTypes, ", "))
+			}
+		}
+	}
+	f.includeObjects = r.IncludeObjects
+	f.excludeObjects = r.ExcludeObjects
+	return &f, nil
+}
+
+// Schema reports whether the schema is selected.
+func (f *Filter) Schema(schema string) bool {
+	if f == nil {
+		return !defaultFilter.excludeSchemas.match(schema)
+	}
+	return f.includeSchemas.allows(schema) && !f.excludeSchemas.match(schema)
+}
+
+// Table reports whether schema.table is selected. It does not check the schema itself.
+func (f *Filter) Table(schema, table string) bool {
+	if f == nil {
+		return true
+	}
+	names := []string{table, schema + "." + table}
+	return f.includeTables.allows(names...) && !f.excludeTables.match(names...)
+}
+
+// Column reports whether the column is selected. It does not check the table itself.
+func (f *Filter) Column(schema, table, column string) bool {
+	if f == nil {
+		return true
+	}
+	names := []string{column, table + "." + column, schema + "." + table + "." + column}
+	return f.includeColumns.allows(names...) && !f.excludeColumns.match(names...)
+}
+
+// Object reports whether constraints/indexes of the given object type are selected.
+func (f *Filter) Object(objectType string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.includeObjects) > 0 && !slices.Contains(f.includeObjects, objectType) {
+		return false
+	}
+	return !slices.Contains(f.excludeObjects, objectType)
+}
+
+// ApplyDatabase removes everything from db that the filter doesn't select.
+func (f *Filter) ApplyDatabase(db *models.DatabaseSnapshot) {
+	for schemaName, schema := range db.Schemas {
+		if !f.Schema(schemaName) {
+			delete(db.Schemas, schemaName)
+			continue
+		}
+		for tblName, t := range schema.Tables {
+			if !f.Table(schemaName, tblName) {
+				delete(schema.Tables, tblName)
+				continue
+			}
+			removed := map[string]bool{}
+			for col := range t.Columns {
+				if !f.Column(schemaName, tblName, col) {
+					delete(t.Columns, col)
+					delete(t.ColumnTypes, col)
+					delete(t.ColumnPositions, col)
+					removed[col] = true
+				}
+			}
+			if len(removed) > 0 {
+				dropReferencing(&t, removed)
+			}
+			if !f.Object(ObjectPrimaryKey) {
+				t.PrimaryKey = nil
+			}
+			if !f.Object(ObjectUnique) {
+				t.UniqueConstraints = nil
+			}
+			if !f.Object(ObjectCheck) {
+				t.CheckConstraints = nil
+			}
+			if !f.Object(ObjectForeignKey) {
+				t.ForeignKeys = nil
+			}
+			if !f.Object(ObjectIndex) {
+				t.Indexes = nil
+			}
+			schema.Tables[tblName] = t
+		}
+	}
+}
+
+// dropReferencing removes the constraints and indexes of t on any of the removed columns, so
+// nothing in the snapshot names a column that isn't in it. A trimmed constraint would be a
+// different constraint, so they're dropped whole.
+func dropReferencing(t *models.TableSnapshot, removed map[string]bool) {
+	uses := func(cols []string) bool {
+		return slices.ContainsFunc(cols, func(c string) bool { return removed[c] })
+	}
+	if uses(t.PrimaryKey) {
+		t.PrimaryKey = nil
+	}
+	for name, cols := range t.UniqueConstraints {
+		if uses(cols) {
+			delete(t.UniqueConstraints, name)
+		}
+	}
+	// check clauses are only text: any mention of a removed column counts
+	mentions := make([]*regexp.Regexp, 0, len(removed))
+	for col := range removed {
+		mentions = append(mentions, regexp.MustCompile(`(^|[^\w$])"?`+regexp.QuoteMeta(col)+`"?($|[^\w$])`))
+	}
+	for name, clause := range t.CheckConstraints {
+		if slices.ContainsFunc(mentions, func(re *regexp.Regexp) bool { return re.MatchString(clause) }) {
+			delete(t.CheckConstraints, name)
+		}
+	}
+	for name, fk := range t.ForeignKeys {
+		if uses(fk.Columns) {
+			delete(t.ForeignKeys, name)
+		}
+	}
+	for name, idx := range t.Indexes {
+		if uses(idx.Columns) {
+			delete(t.Indexes, name)
+		}
+	}
+}
+
+// Set holds the compiled filter of each database, falling back to Default for unlisted ones.
+type Set struct {
+	Default   *Filter
+	Databases map[string]*Filter
+}
+
+// For returns the filter to use for the named database.
+func (s Set) For(db string) *Filter {
+	if f, ok := s.Databases[db]; ok {
+		return f
+	}
+	return s.Default
+}
+
+// Apply filters every database of snap in place.
+func (s Set) Apply(snap *models.Snapshot) {
+	for name, db := range snap.Databases {
+		s.For(name).ApplyDatabase(&db)
+		snap.Databases[name] = db
+	}
+}
+
+// Match reports whether s matches a single glob or "re:" pattern. Invalid patterns never match.
+func Match(pattern, s string) bool {
+	p, err := compilePattern(pattern)
+	if err != nil {
+		return false
+	}
+	return p(s)
+}
+
+// CheckPattern reports whether pattern is a valid glob or "re:" pattern.
+func CheckPattern(pattern string) error {
+	_, err := compilePattern(pattern)
+	return err
+}
+
+// ---------- helpers ----------
+
+var defaultFilter, _ = Rules{}.Compile()
+
+type patterns []func(string) bool
+
+// match reports whether any pattern matches any of the names.
+func (ps patterns) match(names ...string) bool {
+	for _, p := range ps {
+		for _, n := range names {
+			if p(n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allows is match, except that an empty include list allows everything.
+func (ps patterns) allows(names ...string) bool {
+	return len(ps) == 0 || ps.match(names...)
+}
+
+func compilePatterns(src []string) (patterns, error) {
+	ps := make(patterns, 0, len(src))
+	for _, s := range src {
+		p, err := compilePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+func compilePattern(s string) (func(string) bool, error) {
+	if expr, ok := strings.CutPrefix(s, "re:"); ok {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("filter: bad regexp %q: %w", s, err)
+		}
+		return re.MatchString, nil
+	}
+	if _, err := path.Match(s, ""); err != nil {
+		return nil, fmt.Errorf("filter: bad glob %q: %w", s, err)
+	}
+	return func(name string) bool {
+		ok, _ := path.Match(s, name)
+		return ok
+	}, nil
+}
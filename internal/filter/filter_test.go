@@ -0,0 +1,138 @@
+package filter
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"users", "users", true},
+		{"user*", "users", true},
+		{"user*", "orders", false},
+		{"re:^tmp_\\d+$", "tmp_42", true},
+		{"re:^tmp_\\d+$", "tmp_x", false},
+		{"[", "[", false}, // invalid glob never matches
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f, err := Rules{
+		ExcludeSchemas: []string{"audit"},
+		IncludeTables:  []string{"public.*", "billing.invoices"},
+		ExcludeTables:  []string{"*_old"},
+		ExcludeColumns: []string{"secret_*", "users.nick"},
+		ExcludeObjects: []string{ObjectCheck},
+	}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"public schema", f.Schema("public"), true},
+		{"excluded schema", f.Schema("audit"), false},
+		{"system schema", f.Schema("pg_catalog"), false},
+		{"included table", f.Table("public", "users"), true},
+		{"qualified include", f.Table("billing", "invoices"), true},
+		{"not included", f.Table("billing", "payments"), false},
+		{"excluded table", f.Table("public", "users_old"), false},
+		{"column", f.Column("public", "users", "email"), true},
+		{"excluded column", f.Column("public", "users", "secret_key"), false},
+		{"table.column exclude", f.Column("public", "users", "nick"), false},
+		{"table.column elsewhere", f.Column("public", "orders", "nick"), true},
+		{"object", f.Object(ObjectIndex), true},
+		{"excluded object", f.Object(ObjectCheck), false},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, r := range []Rules{
+		{IncludeTables: []string{"re:("}},
+		{ExcludeObjects: []string{"trigger"}},
+	} {
+		if _, err := r.Compile(); err == nil {
+			t.Errorf("Compile(%+v): want error", r)
+		}
+	}
+}
+
+func TestApplyDatabaseDropsReferencesToFilteredColumns(t *testing.T) {
+	db := models.DatabaseSnapshot{Schemas: map[string]models.SchemaSnapshot{
+		"public": {Name: "public", Tables: map[string]models.TableSnapshot{
+			"users": {
+				Name:            "users",
+				Columns:         map[string]string{"id": "integer", "email": "text", "secret": "text"},
+				ColumnTypes:     map[string]string{"id": "integer", "email": "text", "secret": "text"},
+				ColumnPositions: map[string]int{"id": 1, "email": 2, "secret": 3},
+				PrimaryKey:      []string{"id", "secret"},
+				UniqueConstraints: map[string][]string{
+					"users_email_key":  {"email"},
+					"users_secret_key": {"email", "secret"},
+				},
+				CheckConstraints: map[string]string{
+					"users_email_check":  "(email <> ''::text)",
+					"users_secret_check": "(length(secret) > 8)",
+				},
+				ForeignKeys: map[string]models.ForeignKey{
+					"users_secret_fkey": {Name: "users_secret_fkey", Columns: []string{"secret"}, RefSchema: "public", RefTable: "secrets", RefColumns: []string{"id"}},
+				},
+				Indexes: map[string]models.Index{
+					"users_email_idx":  {Name: "users_email_idx", Columns: []string{"email"}},
+					"users_secret_idx": {Name: "users_secret_idx", Columns: []string{"secret"}},
+				},
+			},
+		}},
+	}}
+	f, err := Rules{ExcludeColumns: []string{"secret"}}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.ApplyDatabase(&db)
+
+	u := db.Schemas["public"].Tables["users"]
+	keys := func(m map[string]string) []string { return slices.Sorted(maps.Keys(m)) }
+	if got := keys(u.Columns); !reflect.DeepEqual(got, []string{"email", "id"}) {
+		t.Errorf("columns = %v", got)
+	}
+	if _, ok := u.ColumnTypes["secret"]; ok {
+		t.Error("column type of secret kept")
+	}
+	if _, ok := u.ColumnPositions["secret"]; ok {
+		t.Error("column position of secret kept")
+	}
+	if u.PrimaryKey != nil {
+		t.Errorf("primary key = %v, want none", u.PrimaryKey)
+	}
+	if _, ok := u.UniqueConstraints["users_email_key"]; !ok || len(u.UniqueConstraints) != 1 {
+		t.Errorf("unique constraints = %v", u.UniqueConstraints)
+	}
+	if _, ok := u.CheckConstraints["users_email_check"]; !ok || len(u.CheckConstraints) != 1 {
+		t.Errorf("check constraints = %v", u.CheckConstraints)
+	}
+	if len(u.ForeignKeys) != 0 {
+		t.Errorf("foreign keys = %v", u.ForeignKeys)
+	}
+	if _, ok := u.Indexes["users_email_idx"]; !ok || len(u.Indexes) != 1 {
+		t.Errorf("indexes = %v", u.Indexes)
+	}
+}
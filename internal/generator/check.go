@@ -0,0 +1,129 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"path"
+	"slices"
+	"strings"
+)
+
+// SourceError is a problem gofmt or the type checker found in the code generated for a table.
+type SourceError struct {
+	Table  string // dotted table path
+	Column string // set when the problem is in the column's field
+	File   string // relative to the output directory
+	Line   int
+	Msg    string
+}
+
+func (e *SourceError) Error() string {
+	obj := e.Table
+	if e.Column != "" {
+		obj += "." + e.Column
+	}
+	return fmt.Sprintf("%s (%s:%d): %s", obj, e.File, e.Line, e.Msg)
+}
+
+// generatedFile is a rendered file and the struct it holds.
+type generatedFile struct {
+	st  Struct
+	src []byte
+}
+
+// formatFiles runs gofmt on every file.
+func formatFiles(files []generatedFile) error {
+	var errs []error
+	for i, f := range files {
+		src, err := format.Source(f.src)
+		if err != nil {
+			errs = append(errs, sourceErrors(f, err)...)
+			continue
+		}
+		files[i].src = src
+	}
+	return errors.Join(errs...)
+}
+
+// typeCheck type-checks each generated package. Imports are read from source, so no
+// compiled packages are needed, only GOROOT.
+func typeCheck(files []generatedFile) error {
+	byDir := map[string][]generatedFile{}
+	for _, f := range files {
+		dir := path.Dir(f.st.File)
+		byDir[dir] = append(byDir[dir], f)
+	}
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	slices.Sort(dirs)
+
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "source", nil)
+	var errs []error
+	for _, dir := range dirs {
+		byName := map[string]generatedFile{}
+		var parsed []*ast.File
+		for _, f := range byDir[dir] {
+			af, err := parser.ParseFile(fset, f.st.File, f.src, parser.SkipObjectResolution)
+			if err != nil {
+				errs = append(errs, sourceErrors(f, err)...)
+				continue
+			}
+			byName[f.st.File] = f
+			parsed = append(parsed, af)
+		}
+		conf := types.Config{
+			Importer: imp,
+			Error: func(err error) {
+				if te, ok := err.(types.Error); ok {
+					pos := te.Fset.Position(te.Pos)
+					errs = append(errs, fileError(byName[pos.Filename], pos.Line, te.Msg))
+					return
+				}
+				errs = append(errs, err)
+			},
+		}
+		conf.Check(dir, fset, parsed, nil) // errors go to conf.Error
+	}
+	return errors.Join(errs...)
+}
+
+// sourceErrors turns a gofmt or parser error about f into SourceErrors.
+func sourceErrors(f generatedFile, err error) []error {
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return []error{fileError(f, 0, err.Error())}
+	}
+	out := make([]error, 0, len(list))
+	for _, e := range list {
+		out = append(out, fileError(f, e.Pos.Line, e.Msg))
+	}
+	return out
+}
+
+// fileError blames line of f on the column whose field is declared there, if any.
+func fileError(f generatedFile, line int, msg string) *SourceError {
+	e := &SourceError{Table: f.st.Path(), File: f.st.File, Line: line, Msg: msg}
+	lines := strings.Split(string(f.src), "\n")
+	if line < 1 || line > len(lines) {
+		return e
+	}
+	if words := strings.Fields(lines[line-1]); len(words) > 0 {
+		for _, fld := range f.st.Fields {
+			if fld.Name == words[0] {
+				e.Column = fld.Column
+				break
+			}
+		}
+	}
+	return e
+}
@@ -0,0 +1,175 @@
+package generator
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+)
+
+// Collision strategies for Options.OnCollision.
+const (
+	// OnCollisionSuffix keeps the name for the first source (lower-case SQL names first, then
+	// byte order) and numbers the rest: Order, Order2, Order3. File names get _2, _3 before the extension.
+	OnCollisionSuffix = "suffix"
+	// OnCollisionError makes GenerateStructs fail with a *CollisionError instead.
+	OnCollisionError = "error"
+)
+
+// Collision is a set of tables, columns or files that map to the same Go name or file.
+type Collision struct {
+	Kind    string   `json:"kind"`    // "struct", "field" or "file"
+	Package string   `json:"package"` // package directory, "." for the flat layout
+	Name    string   `json:"name"`    // the name they share
+	Sources []string `json:"sources"` // dotted table or column paths, in resolution order
+	Names   []string `json:"names"`   // the name each source got
+}
+
+func (c Collision) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s in %s:", c.Kind, c.Name, c.Package)
+	for i, src := range c.Sources {
+		fmt.Fprintf(&b, " %s → %s", src, c.Names[i])
+		if i < len(c.Sources)-1 {
+			b.WriteString(",")
+		}
+	}
+	return b.String()
+}
+
+// CollisionError lists the collisions GenerateStructs refused to resolve.
+type CollisionError struct {
+	Collisions []Collision
+}
+
+func (e *CollisionError) Error() string {
+	lines := []string{fmt.Sprintf("generator: %d name collision(s):", len(e.Collisions))}
+	for _, c := range e.Collisions {
+		lines = append(lines, "  "+c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Collisions returns the collisions in the structs generated for snap, and how they were resolved.
+func Collisions(snap *models.Snapshot, opts Options) []Collision {
+	_, collisions := plan(snap, opts)
+	return collisions
+}
+
+// resolveCollisions renames structs, fields and files that would clash, and reports each clash.
+// Go identifiers are case-sensitive but file systems often aren't, so files clash case-insensitively.
+func resolveCollisions(structs []Struct) []Collision {
+	var out []Collision
+
+	byPkg := map[string][]*Struct{}
+	for i := range structs {
+		dir := path.Dir(structs[i].File)
+		byPkg[dir] = append(byPkg[dir], &structs[i])
+	}
+	dirs := make([]string, 0, len(byPkg))
+	for dir := range byPkg {
+		dirs = append(dirs, dir)
+	}
+	slices.Sort(dirs)
+
+	for _, dir := range dirs {
+		pkg := byPkg[dir]
+		// struct names
+		names := make([]*string, len(pkg))
+		sources := make([]string, len(pkg))
+		for i, st := range pkg {
+			names[i], sources[i] = &st.Name, st.Path()
+		}
+		out = append(out, resolve("struct", dir, names, sources, nameKey, suffixName)...)
+
+		// file names
+		files := make([]*string, len(pkg))
+		for i, st := range pkg {
+			files[i] = &st.File
+		}
+		for _, c := range resolve("file", dir, files, sources, strings.ToLower, suffixFile) {
+			c.Name = path.Base(c.Name)
+			for i := range c.Names {
+				c.Names[i] = path.Base(c.Names[i])
+			}
+			out = append(out, c)
+		}
+
+		// field names, per struct
+		for _, st := range pkg {
+			fields := make([]*string, len(st.Fields))
+			cols := make([]string, len(st.Fields))
+			for i := range st.Fields {
+				fields[i], cols[i] = &st.Fields[i].Name, st.Path()+"."+st.Fields[i].Column
+			}
+			for _, c := range resolve("field", dir, fields, cols, nameKey, suffixName) {
+				c.Name = st.Name + "." + c.Name
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
+// caseRank is 1 when the last part of a dotted path has upper case, i.e. was quoted in SQL, else 0.
+func caseRank(src string) int {
+	if last := src[strings.LastIndex(src, ".")+1:]; last != strings.ToLower(last) {
+		return 1
+	}
+	return 0
+}
+
+func nameKey(s string) string { return s }
+
+func suffixName(s string, n int) string { return s + strconv.Itoa(n) }
+
+func suffixFile(s string, n int) string {
+	return strings.TrimSuffix(s, ".go") + "_" + strconv.Itoa(n) + ".go"
+}
+
+// resolve renames the names that share a key, keeping the first by source and numbering the rest
+// with the lowest suffix no other name uses. Unquoted (lower-case) SQL names come first, so
+// "user_id" keeps UserId and "USER_ID" becomes UserId2.
+func resolve(kind, dir string, names []*string, sources []string, key func(string) string, suffix func(string, int) string) []Collision {
+	groups := map[string][]int{}
+	taken := map[string]bool{}
+	for i, n := range names {
+		k := key(*n)
+		groups[k] = append(groups[k], i)
+		taken[k] = true
+	}
+	keys := make([]string, 0, len(groups))
+	for k, idx := range groups {
+		if len(idx) > 1 {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	var out []Collision
+	for _, k := range keys {
+		idx := groups[k]
+		slices.SortFunc(idx, func(a, b int) int {
+			return cmp.Or(cmp.Compare(caseRank(sources[a]), caseRank(sources[b])), strings.Compare(sources[a], sources[b]))
+		})
+		c := Collision{Kind: kind, Package: dir, Name: *names[idx[0]]}
+		for j, i := range idx {
+			if j > 0 {
+				base, n := *names[i], 2
+				for taken[key(suffix(base, n))] {
+					n++
+				}
+				*names[i] = suffix(base, n)
+				taken[key(*names[i])] = true
+			}
+			c.Sources = append(c.Sources, sources[i])
+			c.Names = append(c.Names, *names[i])
+		}
+		out = append(out, c)
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package generator
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/models/modelstest"
+)
+
+func TestCollisions(t *testing.T) {
+	tests := []struct {
+		name   string
+		tables []models.TableSnapshot
+		want   []Collision
+	}{
+		{
+			name:   "none",
+			tables: []models.TableSnapshot{modelstest.Table("users", "id"), modelstest.Table("orders", "id")},
+		},
+		{
+			// lower-case names keep theirs; suffixes skip Order2, which order2 already has
+			name:   "structs and files",
+			tables: []models.TableSnapshot{modelstest.Table("orders", "id"), modelstest.Table("Order", "id"), modelstest.Table("order2", "id"), modelstest.Table("ORDERS", "id")},
+			want: []Collision{
+				{Kind: "struct", Package: ".", Name: "Order",
+					Sources: []string{"app.public.orders", "app.public.ORDERS", "app.public.Order"},
+					Names:   []string{"Order", "Order3", "Order4"}},
+				{Kind: "file", Package: ".", Name: "app_public_orders.go",
+					Sources: []string{"app.public.orders", "app.public.ORDERS"},
+					Names:   []string{"app_public_orders.go", "app_public_orders_2.go"}},
+			},
+		},
+		{
+			name:   "fields",
+			tables: []models.TableSnapshot{modelstest.Table("users", "UserId", "USER_ID", "user_id", "user_id_2")},
+			want: []Collision{
+				{Kind: "field", Package: ".", Name: "User.UserID",
+					Sources: []string{"app.public.users.user_id", "app.public.users.USER_ID", "app.public.users.UserId"},
+					Names:   []string{"UserID", "UserID3", "UserID4"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Collisions(modelstest.Snapshot(tt.tables...), Options{})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Collisions:\n%#v\nwant:\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateStructsOnCollisionError(t *testing.T) {
+	dir := t.TempDir()
+	snap := modelstest.Snapshot(modelstest.Table("users", "id"), modelstest.Table("Users", "id"))
+
+	err := GenerateStructs(snap, dir, Options{OnCollision: OnCollisionError, NoTypeCheck: true})
+	var ce *CollisionError
+	if !errors.As(err, &ce) || len(ce.Collisions) != 2 || ce.Collisions[0].Kind != "struct" {
+		t.Fatalf("err = %v, want a *CollisionError with a struct and a file collision", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+		t.Errorf("wrote %d entries, want nothing on a collision error", len(entries))
+	}
+
+	if err := GenerateStructs(snap, dir, Options{NoTypeCheck: true}); err != nil {
+		t.Fatalf("suffix strategy: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("wrote %d files, want 2", len(entries))
+	}
+}
@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"cmp"
+	"path"
+	"slices"
+
+	"github.com/Saba101/GoMetaSync/internal/gotype"
+	"github.com/Saba101/GoMetaSync/internal/models"
+)
+
+// Struct is the Go type GenerateStructs writes for one table.
+type Struct struct {
+	Name       string `json:"name"`
+	Package    string `json:"package"`
+	ImportPath string `json:"import_path,omitempty"` // set when Options.ImportPath is
+	File       string `json:"file"`                  // slash-separated, relative to the output directory
+
+	DB     string  `json:"db"`
+	Schema string  `json:"schema"`
+	Table  string  `json:"table"`
+	Fields []Field `json:"fields"`
+}
+
+// Field is one field of a generated struct.
+type Field struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Column string `json:"column"`
+}
+
+// Path is the table's dotted path, e.g. "app.public.users".
+func (s Struct) Path() string {
+	return s.DB + "." + s.Schema + "." + s.Table
+}
+
+// Describe returns the structs GenerateStructs would write for snap, sorted by package, name and path,
+// without rendering them. Name collisions are resolved by suffixing, whatever opts.OnCollision says.
+func Describe(snap *models.Snapshot, opts Options) []Struct {
+	structs, _ := plan(snap, opts)
+	return structs
+}
+
+// plan names and places every table's struct and resolves the collisions.
+func plan(snap *models.Snapshot, opts Options) ([]Struct, []Collision) {
+	var out []Struct
+	for dbName, db := range snap.Databases {
+		for schemaName, schema := range db.Schemas {
+			for _, table := range schema.Tables {
+				p := opts.place(dbName, schemaName, table.Name)
+				st := describeTable(opts.namer(), opts.FieldOrder == FieldOrderAlphabetical, p.prefix, dbName, schemaName, &table)
+				st.Package, st.ImportPath, st.File = p.pkg, p.importPath, path.Join(p.dir, p.file)
+				out = append(out, st)
+			}
+		}
+	}
+	collisions := resolveCollisions(out)
+	slices.SortFunc(out, func(a, b Struct) int {
+		return cmp.Or(cmp.Compare(path.Dir(a.File), path.Dir(b.File)), cmp.Compare(a.Name, b.Name), cmp.Compare(a.Path(), b.Path()))
+	})
+	return out, collisions
+}
+
+// describeTable names a table's struct and fields.
+func describeTable(n namer, alphabetical bool, prefix, dbName, schemaName string, t *models.TableSnapshot) Struct {
+	colNames := t.OrderedColumns()
+	if alphabetical {
+		slices.Sort(colNames)
+	}
+
+	st := Struct{Name: n.typeName(prefix, t.Name), DB: dbName, Schema: schemaName, Table: t.Name}
+	for _, col := range colNames {
+		st.Fields = append(st.Fields, Field{Name: n.fieldName(col), Type: gotype.For(t.ColumnType(col)), Column: col})
+	}
+	return st
+}
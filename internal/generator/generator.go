@@ -3,8 +3,10 @@ package generator
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -12,31 +14,51 @@ import (
 )
 
 // GenerateStructs writes Go structs into outDir, one file per table, now enriched with constraint/index metadata.
-func GenerateStructs(snap *models.Snapshot, outDir string) error {
-	if err := os.MkdirAll(outDir, 0o755); err != nil {
+// opts.Layout decides which package (and sub-directory) each struct goes to.
+// Colliding names are suffixed unless opts.OnCollision is OnCollisionError; nothing is written then.
+// The output is gofmt'ed and type-checked first; any problem fails the run with SourceErrors
+// naming the tables and columns at fault, and nothing is written.
+func GenerateStructs(snap *models.Snapshot, outDir string, opts Options) error {
+	if err := opts.validate(); err != nil {
 		return err
 	}
 
-	for dbName, db := range snap.Databases {
-		for schemaName, schema := range db.Schemas {
-			for tableName, table := range schema.Tables {
-				filename := filepath.Join(outDir, fmt.Sprintf("%s_%s_%s.go",
-					sanitize(dbName), sanitize(schemaName), sanitize(tableName)))
+	structs, collisions := plan(snap, opts)
+	if len(collisions) > 0 && opts.OnCollision == OnCollisionError {
+		return &CollisionError{Collisions: collisions}
+	}
 
-				src, err := renderTableFile(dbName, schemaName, &table)
-				if err != nil {
-					return err
-				}
-				if err := os.WriteFile(filename, []byte(src), 0o644); err != nil {
-					return err
-				}
-			}
+	files := make([]generatedFile, 0, len(structs))
+	for _, st := range structs {
+		t := snap.Databases[st.DB].Schemas[st.Schema].Tables[st.Table]
+		src, err := renderTableFile(st, &t)
+		if err != nil {
+			return err
+		}
+		files = append(files, generatedFile{st: st, src: []byte(src)})
+	}
+	if err := formatFiles(files); err != nil {
+		return err
+	}
+	if !opts.NoTypeCheck {
+		if err := typeCheck(files); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range files {
+		dir := filepath.Join(outDir, filepath.FromSlash(path.Dir(f.st.File)))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(outDir, filepath.FromSlash(f.st.File)), f.src, 0o644); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func renderTableFile(dbName, schemaName string, t *models.TableSnapshot) (string, error) {
+func renderTableFile(st Struct, t *models.TableSnapshot) (string, error) {
 	// Build per-column metadata sets
 	pkSet := make(map[string]bool, len(t.PrimaryKey))
 	for _, c := range t.PrimaryKey {
@@ -67,56 +89,47 @@ func renderTableFile(dbName, schemaName string, t *models.TableSnapshot) (string
 		}
 	}
 
-	// Sorted column order (stable output)
-	colNames := make([]string, 0, len(t.Columns))
-	for c := range t.Columns {
-		colNames = append(colNames, c)
-	}
-	sort.Strings(colNames)
-
 	// Build fields
-	fields := make([]field, 0, len(colNames))
-	for _, col := range colNames {
-		goType := mapPgTypeToGo(t.Columns[col])
-		tags := []string{
-			fmt.Sprintf(`json:"%s"`, col),
-			fmt.Sprintf(`db:"%s"`, col),
-		}
+	fields := make([]field, 0, len(st.Fields))
+	for _, f := range st.Fields {
+		col := f.Column
+		tags := []string{tag("json", col), tag("db", col)}
 		if pkSet[col] {
-			tags = append(tags, `pk:"true"`)
+			tags = append(tags, tag("pk", "true"))
 		}
 		if uq := colToUQ[col]; len(uq) > 0 {
 			sort.Strings(uq)
-			tags = append(tags, fmt.Sprintf(`unique:"%s"`, strings.Join(uq, ",")))
+			tags = append(tags, tag("unique", strings.Join(uq, ",")))
 		}
 		if fks := colToFK[col]; len(fks) > 0 {
 			sort.Strings(fks)
-			tags = append(tags, fmt.Sprintf(`fk:"%s"`, strings.Join(fks, ",")))
+			tags = append(tags, tag("fk", strings.Join(fks, ",")))
 		}
 		if idxs := colToIDX[col]; len(idxs) > 0 {
 			sort.Strings(idxs)
-			tags = append(tags, fmt.Sprintf(`indexed:"%s"`, strings.Join(idxs, ",")))
+			tags = append(tags, tag("indexed", strings.Join(idxs, ",")))
 		}
 
 		fields = append(fields, field{
-			Name:    export(col),
-			Type:    goType,
-			TagText: "`" + strings.Join(tags, " ") + "`",
+			Name:    f.Name,
+			Type:    f.Type,
+			TagText: tagLiteral(strings.Join(tags, " ")),
 		})
 	}
 
 	// Build header summaries (pretty comments)
-	header := buildHeaderSummary(dbName, schemaName, t)
+	header := buildHeaderSummary(st.DB, st.Schema, t)
 
 	data := tmplData{
-		Package:   "generated_models",
-		Header:    header,
-		Struct:    export(t.Name),
-		Fields:    fields,
-		Imports:   inferImports(fields),
-		DbName:    dbName,
-		Schema:    schemaName,
-		TableName: t.Name,
+		Package:    st.Package,
+		ImportPath: st.ImportPath,
+		Header:     header,
+		Struct:     st.Name,
+		Fields:     fields,
+		Imports:    inferImports(fields),
+		DbName:     oneLine(st.DB),
+		Schema:     oneLine(st.Schema),
+		TableName:  oneLine(t.Name),
 	}
 
 	var b strings.Builder
@@ -128,78 +141,39 @@ func renderTableFile(dbName, schemaName string, t *models.TableSnapshot) (string
 
 // ---------- helpers ----------
 
-type field struct {
-	Name    string
-	Type    string
-	TagText string
-}
-
-type tmplData struct {
-	Package   string
-	Header    string
-	Struct    string
-	Fields    []field
-	Imports   []string
-	DbName    string
-	Schema    string
-	TableName string
+// tag is one key:"value" pair of a struct tag, with the value quoted the way reflect.StructTag
+// unquotes it, so any column or constraint name round-trips.
+func tag(key, value string) string {
+	return key + ":" + strconv.Quote(value)
 }
 
-func export(s string) string {
-	// simple PascalCase
-	s = strings.TrimSpace(s)
-	parts := strings.FieldsFunc(s, func(r rune) bool {
-		return r == '_' || r == ' ' || r == '-' || r == '.'
-	})
-	for i := range parts {
-		if parts[i] == "" {
-			continue
-		}
-		parts[i] = strings.ToUpper(parts[i][:1]) + strings.ToLower(parts[i][1:])
+// tagLiteral is the Go literal for a struct tag: a raw string, unless the tag holds a backtick.
+func tagLiteral(tag string) string {
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
 	}
-	return strings.Join(parts, "")
+	return "`" + tag + "`"
 }
 
-func sanitize(s string) string {
-	return strings.ReplaceAll(strings.ReplaceAll(strings.ToLower(s), ".", "_"), "-", "_")
+// oneLine escapes line breaks in names that go into // comments.
+var oneLine = strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace
+
+type field struct {
+	Name    string
+	Type    string
+	TagText string
 }
 
-func mapPgTypeToGo(dt string) string {
-	// best-effort mapping; feel free to extend
-	switch strings.ToLower(dt) {
-	case "uuid":
-		return "string"
-	case "text", "varchar", "character varying", "citext":
-		return "string"
-	case "bool", "boolean":
-		return "bool"
-	case "int2", "smallint":
-		return "int16"
-	case "int4", "integer":
-		return "int"
-	case "int8", "bigint":
-		return "int64"
-	case "numeric", "decimal":
-		return "string" // avoid float precision; caller can parse to big.Rat/decimal
-	case "float4", "real":
-		return "float32"
-	case "float8", "double precision":
-		return "float64"
-	case "date":
-		return "time.Time"
-	case "timestamp", "timestamp without time zone", "timestamp with time zone", "timestamptz":
-		return "time.Time"
-	case "json", "jsonb":
-		return "[]byte"
-	case "bytea":
-		return "[]byte"
-	default:
-		// arrays or unrecognized types → string by default
-		if strings.HasSuffix(dt, "[]") {
-			return "[]string"
-		}
-		return "string"
-	}
+type tmplData struct {
+	Package    string
+	ImportPath string
+	Header     string
+	Struct     string
+	Fields     []field
+	Imports    []string
+	DbName     string
+	Schema     string
+	TableName  string
 }
 
 func inferImports(fields []field) []string {
@@ -278,12 +252,15 @@ func buildHeaderSummary(dbName, schema string, t *models.TableSnapshot) string {
 		lines = append(lines, "INDEX: "+strings.Join(keys, " | "))
 	}
 
+	for i := range lines {
+		lines[i] = oneLine(lines[i])
+	}
 	return strings.Join(lines, "\n// ")
 }
 
 var fileTmpl = template.Must(template.New("file").Parse(`// {{.Header}}
 
-package {{.Package}}
+package {{.Package}}{{if .ImportPath}} // import "{{.ImportPath}}"{{end}}
 
 {{- if .Imports }}
 import (
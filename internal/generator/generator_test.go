@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/models/modelstest"
+)
+
+// TestGenerateStructsOddNames checks that legal but awkward SQL names still produce code that
+// gofmt and the type checker accept, with tags that give the names back.
+func TestGenerateStructsOddNames(t *testing.T) {
+	cols := []string{"id", "we`ird", `quo"te`, "new\nline", `back\slash`, "1st_place", "名前", "type"}
+	tbl := models.TableSnapshot{
+		Name:             "odd\ntable",
+		Columns:          map[string]string{},
+		PrimaryKey:       []string{"we`ird"},
+		CheckConstraints: map[string]string{"odd\ncheck": "(id > 0)"},
+		UniqueConstraints: map[string][]string{
+			`uq"name`: {`quo"te`},
+		},
+	}
+	for _, c := range cols {
+		tbl.Columns[c] = "text"
+	}
+
+	dir := t.TempDir()
+	if err := GenerateStructs(modelstest.Snapshot(tbl), dir, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	if len(files) != 1 {
+		t.Fatalf("files = %v, want one", files)
+	}
+	src, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), files[0], src, 0)
+	if err != nil {
+		t.Fatalf("generated code doesn't parse: %v\n%s", err, src)
+	}
+
+	got := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if field, ok := n.(*ast.Field); ok && field.Tag != nil {
+			raw, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				t.Errorf("tag %s: %v", field.Tag.Value, err)
+				return true
+			}
+			got[reflect.StructTag(raw).Get("db")] = true
+		}
+		return true
+	})
+	for _, c := range cols {
+		if !got[c] {
+			t.Errorf("no field with db tag %q in\n%s", c, src)
+		}
+	}
+}
+
+func TestSourceErrorsPointAtColumns(t *testing.T) {
+	f := generatedFile{
+		st: Struct{DB: "app", Schema: "public", Table: "users", File: "users.go", Fields: []Field{
+			{Name: "ID", Type: "int", Column: "id"},
+			{Name: "Bad", Type: "int", Column: "bad"},
+		}},
+		src: []byte("package p\n\ntype User struct {\n\tID int\n\tBad int int\n}\n"),
+	}
+	err := formatFiles([]generatedFile{f})
+	if err == nil {
+		t.Fatal("want a gofmt error")
+	}
+	var se *SourceError
+	if !errors.As(err, &se) {
+		t.Fatalf("error %v is not a *SourceError", err)
+	}
+	if se.Table != "app.public.users" || se.Column != "bad" || se.Line != 5 {
+		t.Errorf("error = %+v, want app.public.users column bad line 5", se)
+	}
+
+	f.src = []byte("package p\n\ntype User struct {\n\tID int\n\tBad undefinedType\n}\n")
+	err = typeCheck([]generatedFile{f})
+	if !errors.As(err, &se) || se.Column != "bad" {
+		t.Errorf("type check error = %v, want one on column bad", err)
+	}
+}
+
+// Arrays are generated as string whether the snapshot has their full type or only their data_type.
+func TestDescribeArrayFields(t *testing.T) {
+	withTypes := models.TableSnapshot{
+		Name:        "posts",
+		Columns:     map[string]string{"id": "integer", "tags": "ARRAY"},
+		ColumnTypes: map[string]string{"id": "integer", "tags": "text[]"},
+	}
+	withoutTypes := models.TableSnapshot{Name: "notes", Columns: map[string]string{"id": "integer", "tags": "ARRAY"}}
+
+	for _, st := range Describe(modelstest.Snapshot(withTypes, withoutTypes), Options{}) {
+		types := map[string]string{}
+		for _, f := range st.Fields {
+			types[f.Column] = f.Type
+		}
+		if want := map[string]string{"id": "int", "tags": "string"}; !reflect.DeepEqual(types, want) {
+			t.Errorf("%s fields = %v, want %v", st.Table, types, want)
+		}
+	}
+}
+
+func TestDescribeFieldOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		positions map[string]int
+		want      []string
+	}{
+		// attnums skip dropped columns
+		{"table order", map[string]int{"id": 1, "name": 3, "created_at": 7, "email": 4}, []string{"id", "name", "email", "created_at"}},
+		{"older snapshot without positions", nil, []string{"created_at", "email", "id", "name"}},
+		{"unpositioned columns last", map[string]int{"name": 2, "id": 5}, []string{"name", "id", "created_at", "email"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tbl := modelstest.Table("users", "id", "name", "email", "created_at")
+			tbl.ColumnPositions = tt.positions
+			var got []string
+			for _, f := range Describe(modelstest.Snapshot(tbl), Options{})[0].Fields {
+				got = append(got, f.Column)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
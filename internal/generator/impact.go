@@ -0,0 +1,152 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+)
+
+// StructChange is a struct that appears or disappears between two snapshots.
+type StructChange struct {
+	Package string `json:"package"`
+	Struct  string `json:"struct"`
+	Table   string `json:"table"` // dotted table path
+}
+
+// FieldChange is a field that appears, disappears or changes type.
+type FieldChange struct {
+	Package string `json:"package"`
+	Struct  string `json:"struct"`
+	Field   string `json:"field"`
+	Column  string `json:"column"` // dotted column path
+	OldType string `json:"old_type,omitempty"`
+	NewType string `json:"new_type,omitempty"`
+}
+
+// Impact is what regenerating the structs would change in the Go API.
+// Removed structs and fields and retyped fields break code that uses them; additions don't.
+type Impact struct {
+	StructsAdded   []StructChange `json:"structs_added"`
+	StructsRemoved []StructChange `json:"structs_removed"`
+	FieldsAdded    []FieldChange  `json:"fields_added"`
+	FieldsRemoved  []FieldChange  `json:"fields_removed"`
+	FieldsRetyped  []FieldChange  `json:"fields_retyped"`
+}
+
+// Breaking counts the changes that can stop existing code compiling.
+func (im Impact) Breaking() int {
+	return len(im.StructsRemoved) + len(im.FieldsRemoved) + len(im.FieldsRetyped)
+}
+
+// Additive counts the changes that can't.
+func (im Impact) Additive() int {
+	return len(im.StructsAdded) + len(im.FieldsAdded)
+}
+
+// CompareAPI compares the structs generated for two snapshots with the same options. Structs are
+// matched by package and name, as Go code refers to them, so a renamed table is a removed struct
+// plus an added one.
+func CompareAPI(oldSnap, newSnap *models.Snapshot, opts Options) Impact {
+	im := Impact{
+		StructsAdded: []StructChange{}, StructsRemoved: []StructChange{},
+		FieldsAdded: []FieldChange{}, FieldsRemoved: []FieldChange{}, FieldsRetyped: []FieldChange{},
+	}
+	oldList, newList := Describe(oldSnap, opts), Describe(newSnap, opts)
+	oldStructs, newStructs := byName(oldList), byName(newList)
+
+	for _, ns := range newList {
+		prev, ok := oldStructs[ns.key()]
+		if !ok {
+			im.StructsAdded = append(im.StructsAdded, StructChange{Package: ns.Package, Struct: ns.Name, Table: ns.Path()})
+			continue
+		}
+		oldFields := map[string]Field{}
+		for _, f := range prev.Fields {
+			oldFields[f.Name] = f
+		}
+		newFields := map[string]bool{}
+		for _, f := range ns.Fields {
+			newFields[f.Name] = true
+			of, ok := oldFields[f.Name]
+			switch {
+			case !ok:
+				im.FieldsAdded = append(im.FieldsAdded, FieldChange{Package: ns.Package, Struct: ns.Name, Field: f.Name, Column: ns.Path() + "." + f.Column, NewType: f.Type})
+			case of.Type != f.Type:
+				im.FieldsRetyped = append(im.FieldsRetyped, FieldChange{Package: ns.Package, Struct: ns.Name, Field: f.Name, Column: ns.Path() + "." + f.Column, OldType: of.Type, NewType: f.Type})
+			}
+		}
+		for _, f := range prev.Fields {
+			if !newFields[f.Name] {
+				im.FieldsRemoved = append(im.FieldsRemoved, FieldChange{Package: prev.Package, Struct: prev.Name, Field: f.Name, Column: prev.Path() + "." + f.Column, OldType: f.Type})
+			}
+		}
+	}
+	for _, prev := range oldList {
+		if _, ok := newStructs[prev.key()]; !ok {
+			im.StructsRemoved = append(im.StructsRemoved, StructChange{Package: prev.Package, Struct: prev.Name, Table: prev.Path()})
+		}
+	}
+	return im
+}
+
+// key identifies a struct as Go code sees it: its package directory and name.
+func (s Struct) key() string {
+	return path.Dir(s.File) + "/" + s.Name
+}
+
+// byName indexes structs by package and name; with duplicate names (see GenerateStructs) the first one wins.
+func byName(structs []Struct) map[string]Struct {
+	m := map[string]Struct{}
+	for _, s := range structs {
+		if _, ok := m[s.key()]; !ok {
+			m[s.key()] = s
+		}
+	}
+	return m
+}
+
+// Fprint writes the impact report to w.
+func (im Impact) Fprint(w io.Writer) {
+	if im.Breaking()+im.Additive() == 0 {
+		fmt.Fprintln(w, "✅ No Go API changes")
+		return
+	}
+	fmt.Fprintf(w, "🧩 Go API impact: %d breaking, %d additive\n", im.Breaking(), im.Additive())
+	for _, s := range im.StructsRemoved {
+		fmt.Fprintf(w, "  ❌ Struct removed: %s (%s)\n", qualify(s.Package, s.Struct), s.Table)
+	}
+	for _, f := range im.FieldsRemoved {
+		fmt.Fprintf(w, "  ❌ Field removed: %s.%s %s (%s)\n", qualify(f.Package, f.Struct), f.Field, f.OldType, f.Column)
+	}
+	for _, f := range im.FieldsRetyped {
+		fmt.Fprintf(w, "  ⚠️ Field retyped: %s.%s %s → %s (%s)\n", qualify(f.Package, f.Struct), f.Field, f.OldType, f.NewType, f.Column)
+	}
+	for _, s := range im.StructsAdded {
+		fmt.Fprintf(w, "  ✅ Struct added: %s (%s)\n", qualify(s.Package, s.Struct), s.Table)
+	}
+	for _, f := range im.FieldsAdded {
+		fmt.Fprintf(w, "  ✅ Field added: %s.%s %s (%s)\n", qualify(f.Package, f.Struct), f.Field, f.NewType, f.Column)
+	}
+}
+
+// qualify prefixes a struct name with its package unless it is in the flat layout's default package.
+func qualify(pkg, name string) string {
+	if pkg == "" || pkg == DefaultPackage {
+		return name
+	}
+	return pkg + "." + name
+}
+
+// JSON writes the impact report as indented JSON.
+func (im Impact) JSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Breaking int `json:"breaking"`
+		Additive int `json:"additive"`
+		Impact
+	}{im.Breaking(), im.Additive(), im})
+}
@@ -0,0 +1,51 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/models/modelstest"
+)
+
+func TestCompareAPI(t *testing.T) {
+	oldSnap := modelstest.Snapshot(
+		models.TableSnapshot{Name: "users", Columns: map[string]string{"id": "integer", "nickname": "text"}},
+		modelstest.Table("audit", "note"),
+	)
+	newSnap := modelstest.Snapshot(
+		models.TableSnapshot{Name: "users", Columns: map[string]string{"id": "bigint", "email": "text"}},
+		modelstest.Table("orders", "note"),
+	)
+	im := CompareAPI(oldSnap, newSnap, Options{})
+
+	var got []string
+	for _, s := range im.StructsAdded {
+		got = append(got, "+struct "+s.Struct)
+	}
+	for _, s := range im.StructsRemoved {
+		got = append(got, "-struct "+s.Struct)
+	}
+	for _, f := range im.FieldsAdded {
+		got = append(got, "+field "+f.Struct+"."+f.Field+" "+f.NewType)
+	}
+	for _, f := range im.FieldsRemoved {
+		got = append(got, "-field "+f.Struct+"."+f.Field+" "+f.OldType)
+	}
+	for _, f := range im.FieldsRetyped {
+		got = append(got, "~field "+f.Struct+"."+f.Field+" "+f.OldType+" -> "+f.NewType)
+	}
+	want := []string{
+		"+struct Order",
+		"-struct Audit",
+		"+field User.Email string",
+		"-field User.Nickname string",
+		"~field User.ID int -> int64",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("impact:\n%q\nwant:\n%q", got, want)
+	}
+	if im.Breaking() != 3 || im.Additive() != 2 {
+		t.Errorf("Breaking() = %d, Additive() = %d, want 3 and 2", im.Breaking(), im.Additive())
+	}
+}
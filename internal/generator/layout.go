@@ -0,0 +1,145 @@
+package generator
+
+import (
+	"fmt"
+	"go/token"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// Layout is how GenerateStructs spreads structs over packages.
+type Layout string
+
+const (
+	// LayoutFlat puts every struct in one package. Type names are prefixed with the schema
+	// unless it is public, and with the database when Options.DatabasePrefix is set.
+	LayoutFlat Layout = "flat"
+	// LayoutDatabase writes one package per database, outDir/<db>; type names are prefixed
+	// with the schema unless it is public.
+	LayoutDatabase Layout = "database"
+	// LayoutSchema writes one package per schema, outDir/<db>/<schema>.
+	LayoutSchema Layout = "schema"
+)
+
+// Layouts lists the supported layouts.
+var Layouts = []Layout{LayoutFlat, LayoutDatabase, LayoutSchema}
+
+// Field orders for Options.FieldOrder.
+const (
+	// FieldOrderOrdinal emits fields in table column order, so SELECT * scans in struct order.
+	// Snapshots without column positions fall back to alphabetical order.
+	FieldOrderOrdinal = "ordinal"
+	// FieldOrderAlphabetical emits fields sorted by column name.
+	FieldOrderAlphabetical = "alphabetical"
+)
+
+// DefaultPackage is the package name of the flat layout when Options.Package is empty.
+const DefaultPackage = "generated_models"
+
+// Options controls GenerateStructs.
+type Options struct {
+	Layout Layout // default LayoutFlat
+	// Package names the flat layout's package. Per-database and per-schema packages are
+	// named after their database or schema.
+	Package string
+	// ImportPath is the import path of outDir, e.g. "github.com/acme/app/models". When set,
+	// every file gets an import comment with its package's import path.
+	ImportPath string
+	// Initialisms are written in upper case in names, on top of DefaultInitialisms unless
+	// NoDefaultInitialisms is set.
+	Initialisms          []string
+	NoDefaultInitialisms bool
+	// PluralNames keeps struct names as plural as their tables; by default users → User.
+	PluralNames bool
+	// DatabasePrefix prefixes type names in the flat layout with their database, e.g. AppUser,
+	// for snapshots of several databases. It is an option rather than inferred from the number of
+	// databases so that adding a database to a snapshot doesn't rename every existing struct.
+	DatabasePrefix bool
+	// FieldOrder is FieldOrderOrdinal (default) or FieldOrderAlphabetical.
+	FieldOrder string
+	// NoTypeCheck skips type-checking the generated packages, e.g. where GOROOT has no sources.
+	NoTypeCheck bool
+	// OnCollision is OnCollisionSuffix (default) or OnCollisionError; see Collision.
+	OnCollision string
+}
+
+func (o Options) validate() error {
+	if o.Layout != "" && o.Layout != LayoutFlat && o.Layout != LayoutDatabase && o.Layout != LayoutSchema {
+		return fmt.Errorf("generator: unknown layout %q (want flat, database or schema)", o.Layout)
+	}
+	if o.FieldOrder != "" && o.FieldOrder != FieldOrderOrdinal && o.FieldOrder != FieldOrderAlphabetical {
+		return fmt.Errorf("generator: unknown field order %q (want ordinal or alphabetical)", o.FieldOrder)
+	}
+	if o.OnCollision != "" && o.OnCollision != OnCollisionSuffix && o.OnCollision != OnCollisionError {
+		return fmt.Errorf("generator: unknown collision strategy %q (want suffix or error)", o.OnCollision)
+	}
+	if o.Package != "" && !token.IsIdentifier(o.Package) {
+		return fmt.Errorf("generator: package name %q is not a Go identifier", o.Package)
+	}
+	return nil
+}
+
+// placement is where a table's struct goes.
+type placement struct {
+	dir        string // relative to outDir, "" for the flat layout
+	file       string // file name within dir
+	pkg        string
+	importPath string
+	prefix     string // prepended to the exported table name
+}
+
+func (o Options) place(dbName, schemaName, tableName string) placement {
+	var p placement
+	n := o.namer()
+	schemaPrefix := ""
+	if schemaName != "public" {
+		schemaPrefix = n.prefix(schemaName)
+	}
+
+	switch o.Layout {
+	case LayoutDatabase:
+		p.dir = packageName(dbName)
+		p.pkg = packageName(dbName)
+		p.file = goFile(schemaName, tableName)
+		p.prefix = schemaPrefix
+	case LayoutSchema:
+		p.dir = path.Join(packageName(dbName), packageName(schemaName))
+		p.pkg = packageName(schemaName)
+		p.file = goFile(tableName)
+	default:
+		p.pkg = o.Package
+		if p.pkg == "" {
+			p.pkg = DefaultPackage
+		}
+		p.file = goFile(dbName, schemaName, tableName)
+		p.prefix = schemaPrefix
+		if o.DatabasePrefix {
+			p.prefix = n.prefix(dbName) + p.prefix
+		}
+	}
+	if o.ImportPath != "" {
+		p.importPath = path.Join(o.ImportPath, p.dir)
+	}
+	return p
+}
+
+// packageName turns a database or schema name into a valid package (and directory) name.
+func packageName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "db_" + name
+	}
+	if token.IsKeyword(name) {
+		name += "_"
+	}
+	return name
+}
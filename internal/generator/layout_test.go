@@ -0,0 +1,75 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/models/modelstest"
+)
+
+// appAndCRM is a snapshot of two databases: app with public.users and billing.invoices, crm with public.users.
+func appAndCRM() *models.Snapshot {
+	snap := modelstest.Snapshot(modelstest.Table("users", "id"))
+	app := snap.Databases["app"]
+	app.Schemas["billing"] = models.SchemaSnapshot{Name: "billing", Tables: map[string]models.TableSnapshot{
+		"invoices": modelstest.Table("invoices", "id"),
+	}}
+	snap.Databases["crm"] = models.DatabaseSnapshot{DBName: "crm", Schemas: map[string]models.SchemaSnapshot{
+		"public": {Name: "public", Tables: map[string]models.TableSnapshot{"users": modelstest.Table("users", "id")}},
+	}}
+	return snap
+}
+
+func TestLayouts(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want []string // package, name and file of each struct
+	}{
+		{"flat", Options{}, []string{
+			"generated_models BillingInvoice app_billing_invoices.go",
+			"generated_models User app_public_users.go",
+			"generated_models User2 crm_public_users.go",
+		}},
+		{"flat with database prefix", Options{DatabasePrefix: true, Package: "models"}, []string{
+			"models AppBillingInvoice app_billing_invoices.go",
+			"models AppUser app_public_users.go",
+			"models CrmUser crm_public_users.go",
+		}},
+		{"database", Options{Layout: LayoutDatabase}, []string{
+			"app BillingInvoice app/billing_invoices.go",
+			"app User app/public_users.go",
+			"crm User crm/public_users.go",
+		}},
+		{"schema", Options{Layout: LayoutSchema}, []string{
+			"billing Invoice app/billing/invoices.go",
+			"public User app/public/users.go",
+			"public User crm/public/users.go",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, st := range Describe(appAndCRM(), tt.opts) {
+				got = append(got, st.Package+" "+st.Name+" "+st.File)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("structs:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Adding a database to a snapshot leaves the structs of the others alone.
+func TestAddingDatabaseKeepsNames(t *testing.T) {
+	oneDB := appAndCRM()
+	delete(oneDB.Databases, "crm")
+
+	for _, opts := range []Options{{}, {DatabasePrefix: true}} {
+		im := CompareAPI(oneDB, appAndCRM(), opts)
+		if im.Breaking() != 0 || len(im.StructsAdded) != 1 {
+			t.Errorf("DatabasePrefix=%v: impact = %+v, want only the crm struct added", opts.DatabasePrefix, im)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package generator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DefaultInitialisms are written in upper case in generated names, as golint wants:
+// user_id → UserID, api_url → APIURL.
+var DefaultInitialisms = []string{
+	"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF", "GUID", "HTML", "HTTP", "HTTPS", "ID",
+	"IP", "JSON", "LHS", "QPS", "RAM", "RHS", "RPC", "SLA", "SMTP", "SQL", "SSH", "TCP",
+	"TLS", "TTL", "UDP", "UI", "UID", "UUID", "URI", "URL", "UTF8", "VM", "XML", "XMPP",
+	"XSRF", "XSS",
+}
+
+// namer turns SQL names into Go identifiers.
+type namer struct {
+	initialisms map[string]bool
+	plural      bool
+}
+
+func (o Options) namer() namer {
+	n := namer{initialisms: map[string]bool{}, plural: o.PluralNames}
+	if !o.NoDefaultInitialisms {
+		for _, s := range DefaultInitialisms {
+			n.initialisms[s] = true
+		}
+	}
+	for _, s := range o.Initialisms {
+		n.initialisms[strings.ToUpper(strings.TrimSpace(s))] = true
+	}
+	return n
+}
+
+// typeName is the struct name for a table: singular unless PluralNames is set, users → User.
+func (n namer) typeName(prefix, table string) string {
+	words := splitWords(table)
+	if !n.plural && len(words) > 0 {
+		words[len(words)-1] = singular(words[len(words)-1])
+	}
+	return ident(prefix + n.join(words))
+}
+
+// fieldName is the struct field name for a column.
+func (n namer) fieldName(col string) string {
+	return ident(n.join(splitWords(col)))
+}
+
+// prefix is the PascalCase form of a database or schema name, prepended to type names.
+func (n namer) prefix(s string) string {
+	return n.join(splitWords(s))
+}
+
+// join PascalCases words, upper-casing initialisms and their plurals (IDs).
+func (n namer) join(words []string) string {
+	var b strings.Builder
+	for _, w := range words {
+		up := strings.ToUpper(w)
+		switch {
+		case n.initialisms[up]:
+			b.WriteString(up)
+		case len(up) > 1 && strings.HasSuffix(up, "S") && n.initialisms[up[:len(up)-1]]:
+			b.WriteString(up[:len(up)-1] + "s")
+		default:
+			r := []rune(strings.ToLower(w))
+			r[0] = unicode.ToUpper(r[0])
+			b.WriteString(string(r))
+		}
+	}
+	return b.String()
+}
+
+// splitWords splits a SQL name at anything that isn't a letter or digit, and at camelCase
+// humps: "userId" → user Id, "HTTPServer" → HTTP Server.
+func splitWords(s string) []string {
+	var words []string
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		r := []rune(part)
+		start := 0
+		for i := 1; i < len(r); i++ {
+			lowerToUpper := !unicode.IsUpper(r[i-1]) && unicode.IsUpper(r[i])
+			acronymEnd := unicode.IsUpper(r[i-1]) && unicode.IsUpper(r[i]) && i+1 < len(r) && unicode.IsLower(r[i+1])
+			if lowerToUpper || acronymEnd {
+				words = append(words, string(r[start:i]))
+				start = i
+			}
+		}
+		words = append(words, string(r[start:]))
+	}
+	return words
+}
+
+// ident makes s a valid exported identifier: names that are empty or don't start with an
+// upper-case letter (digits, letters without case) get an X in front. Go keywords are all lower
+// case, so an exported name can't be one; package names are escaped by packageName.
+func ident(s string) string {
+	if s == "" || !unicode.IsUpper([]rune(s)[0]) {
+		return "X" + s
+	}
+	return s
+}
+
+var irregular = map[string]string{
+	"people": "person", "children": "child", "men": "man", "women": "woman",
+	"mice": "mouse", "geese": "goose", "feet": "foot", "teeth": "tooth",
+	"movies": "movie", "cookies": "cookie", "pies": "pie", "ties": "tie",
+	// -ices is usually -ice (prices, invoices, services); these are the Latin ones
+	"indices": "index", "vertices": "vertex", "matrices": "matrix", "appendices": "appendix",
+}
+
+// singular is a best-effort English singular of a word, keeping its case; words it doesn't
+// recognise as plural are returned unchanged.
+func singular(w string) string {
+	lw := strings.ToLower(w)
+	if s, ok := irregular[lw]; ok {
+		return w[:1] + s[1:]
+	}
+	switch {
+	case len(lw) < 3 || strings.IndexFunc(lw, func(r rune) bool { return r > unicode.MaxASCII }) >= 0:
+		return w
+	case lw == "series" || lw == "species" || lw == "news":
+		return w
+	case strings.HasSuffix(lw, "ies") && len(lw) > 4:
+		return w[:len(w)-3] + matchCase(w[len(w)-3:], "y")
+	case strings.HasSuffix(lw, "yses"):
+		// analyses → analysis
+		return w[:len(w)-2] + matchCase(w[len(w)-2:], "is")
+	case strings.HasSuffix(lw, "uses"):
+		// statuses → status, buses → bus; but houses → house, causes → cause
+		if len(lw) == 4 || strings.ContainsRune("aeiou", rune(lw[len(lw)-5])) {
+			return w[:len(w)-1]
+		}
+		return w[:len(w)-2]
+	case strings.HasSuffix(lw, "iases"):
+		// aliases → alias, biases → bias; but databases → database
+		return w[:len(w)-2]
+	case strings.HasSuffix(lw, "sses"), strings.HasSuffix(lw, "xes"), strings.HasSuffix(lw, "ches"),
+		strings.HasSuffix(lw, "shes"), strings.HasSuffix(lw, "zzes"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(lw, "ss"), strings.HasSuffix(lw, "us"), strings.HasSuffix(lw, "is"):
+		return w
+	case strings.HasSuffix(lw, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+// matchCase upper-cases repl when like is all upper case.
+func matchCase(like, repl string) string {
+	if like == strings.ToUpper(like) {
+		return strings.ToUpper(repl)
+	}
+	return repl
+}
+
+// fileSafe turns a SQL name into a file name part: lower case, with anything that isn't a letter
+// or digit replaced by _.
+func fileSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, s)
+}
+
+// constrainedSuffixes end file names that the go tool reads as build constraints.
+var constrainedSuffixes = map[string]bool{
+	"test": true,
+	// GOOS
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true,
+	"illumos": true, "ios": true, "js": true, "linux": true, "nacl": true, "netbsd": true,
+	"openbsd": true, "plan9": true, "solaris": true, "wasip1": true, "windows": true, "zos": true,
+	// GOARCH
+	"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true,
+	"arm64be": true, "loong64": true, "mips": true, "mipsle": true, "mips64": true,
+	"mips64le": true, "mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true,
+	"ppc64le": true, "riscv": true, "riscv64": true, "s390": true, "s390x": true, "sparc": true,
+	"sparc64": true, "wasm": true,
+}
+
+// goFile joins name parts into a .go file name the go tool won't skip: users_test.go would be a
+// test file and app_windows.go Windows-only, so those get a _table suffix.
+func goFile(parts ...string) string {
+	for i, p := range parts {
+		parts[i] = fileSafe(p)
+	}
+	base := strings.Join(parts, "_")
+	if i := strings.LastIndex(base, "_"); i >= 0 && constrainedSuffixes[base[i+1:]] {
+		base += "_table"
+	}
+	return base + ".go"
+}
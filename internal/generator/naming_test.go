@@ -0,0 +1,124 @@
+package generator
+
+import "testing"
+
+func TestSingular(t *testing.T) {
+	tests := map[string]string{
+		"users":      "user",
+		"categories": "category",
+		"boxes":      "box",
+		"addresses":  "address",
+		"matches":    "match",
+		"statuses":   "status",
+		"buses":      "bus",
+		"houses":     "house",
+		"causes":     "cause",
+		"uses":       "use",
+		"aliases":    "alias",
+		"databases":  "database",
+		"cases":      "case",
+		"analyses":   "analysis",
+		"indices":    "index",
+		"invoices":   "invoice",
+		"prices":     "price",
+		"people":     "person",
+		"movies":     "movie",
+		"series":     "series",
+		"status":     "status",
+		"address":    "address",
+		"data":       "data",
+		"Users":      "User",
+		"STATUSES":   "STATUS",
+	}
+	for plural, want := range tests {
+		if got := singular(plural); got != want {
+			t.Errorf("singular(%q) = %q, want %q", plural, got, want)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	n := Options{}.namer()
+	fields := []struct{ col, want string }{
+		{"user_id", "UserID"},
+		{"api_url", "APIURL"},
+		{"url_api_json", "URLAPIJSON"},
+		{"user_ids", "UserIDs"},
+		{"userId", "UserID"},
+		{"HTTPServer", "HTTPServer"},
+		{"created-at", "CreatedAt"},
+		{"type", "Type"}, // exported names can't be keywords
+		{"func", "Func"},
+		{"1st_place", "X1stPlace"},
+		{"2fa", "X2fa"},
+		{"___", "X"},
+		{"名前", "X名前"}, // letters without case can't be exported
+		{"größe", "Größe"},
+		{"émail", "Émail"},
+		{"ñandú_id", "ÑandúID"},
+	}
+	for _, tt := range fields {
+		if got := n.fieldName(tt.col); got != tt.want {
+			t.Errorf("fieldName(%q) = %q, want %q", tt.col, got, tt.want)
+		}
+	}
+
+	types := []struct{ prefix, table, want string }{
+		{"", "users", "User"},
+		{"", "order_items", "OrderItem"},
+		{"", "user_statuses", "UserStatus"},
+		{"Billing", "invoices", "BillingInvoice"},
+		{"", "1st_places", "X1stPlace"},
+		{"", "api_keys", "APIKey"},
+	}
+	for _, tt := range types {
+		if got := n.typeName(tt.prefix, tt.table); got != tt.want {
+			t.Errorf("typeName(%q, %q) = %q, want %q", tt.prefix, tt.table, got, tt.want)
+		}
+	}
+
+	if got := (Options{PluralNames: true}).namer().typeName("", "users"); got != "Users" {
+		t.Errorf("PluralNames: typeName(users) = %q, want Users", got)
+	}
+	custom := Options{Initialisms: []string{"sku"}, NoDefaultInitialisms: true}.namer()
+	if got := custom.fieldName("sku_id"); got != "SKUId" {
+		t.Errorf("custom initialisms: fieldName(sku_id) = %q, want SKUId", got)
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	tests := map[string]string{
+		"public":     "public",
+		"Billing":    "billing",
+		"my-db":      "my_db",
+		"type":       "type_",
+		"func":       "func_",
+		"2024_data":  "db_2024_data",
+		"":           "db_",
+		"données":    "données",
+		"sales.east": "sales_east",
+	}
+	for in, want := range tests {
+		if got := packageName(in); got != want {
+			t.Errorf("packageName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGoFile(t *testing.T) {
+	tests := []struct {
+		parts []string
+		want  string
+	}{
+		{[]string{"app", "public", "users"}, "app_public_users.go"},
+		{[]string{"users_test"}, "users_test_table.go"},
+		{[]string{"app", "public", "windows"}, "app_public_windows_table.go"},
+		{[]string{"amd64"}, "amd64.go"}, // only a suffix after _ constrains
+		{[]string{"Sales Data/2024"}, "sales_data_2024.go"},
+	}
+	for _, tt := range tests {
+		if got := goFile(tt.parts...); got != tt.want {
+			t.Errorf("goFile(%q) = %q, want %q", tt.parts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Package gotype maps PostgreSQL column types to the Go types the generator emits for them.
+// It is shared by the generator and the diff's type-change classifier.
+package gotype
+
+import "strings"
+
+// For returns the Go field type generated for a column type: an information_schema
+// data_type, or a full type with modifiers such as "character varying(50)" or "numeric(10,2)[]".
+func For(pgType string) string {
+	t := strings.TrimSpace(pgType)
+	// modifiers don't change the Go type: "timestamp(3) with time zone" → "timestamp with time zone"
+	if open := strings.Index(t, "("); open >= 0 {
+		if end := strings.Index(t[open:], ")"); end >= 0 {
+			t = strings.Join(strings.Fields(t[:open]+t[open+end+1:]), " ")
+			t = strings.ReplaceAll(t, " []", "[]")
+		}
+	}
+	// arrays keep the type generated for their information_schema data_type, ARRAY
+	if strings.HasSuffix(t, "[]") {
+		t = "ARRAY"
+	}
+	return mapPgTypeToGo(t)
+}
+
+func mapPgTypeToGo(dt string) string {
+	// best-effort mapping; feel free to extend
+	switch strings.ToLower(dt) {
+	case "uuid":
+		return "string"
+	case "text", "varchar", "character varying", "citext":
+		return "string"
+	case "bool", "boolean":
+		return "bool"
+	case "int2", "smallint":
+		return "int16"
+	case "int4", "integer":
+		return "int"
+	case "int8", "bigint":
+		return "int64"
+	case "numeric", "decimal":
+		return "string" // avoid float precision; caller can parse to big.Rat/decimal
+	case "float4", "real":
+		return "float32"
+	case "float8", "double precision":
+		return "float64"
+	case "date":
+		return "time.Time"
+	case "timestamp", "timestamp without time zone", "timestamp with time zone", "timestamptz":
+		return "time.Time"
+	case "json", "jsonb":
+		return "[]byte"
+	case "bytea":
+		return "[]byte"
+	default:
+		// arrays or unrecognized types → string by default
+		if strings.HasSuffix(dt, "[]") {
+			return "[]string"
+		}
+		return "string"
+	}
+}
@@ -0,0 +1,23 @@
+package gotype
+
+import "testing"
+
+func TestFor(t *testing.T) {
+	tests := map[string]string{
+		"integer":                     "int",
+		"bigint":                      "int64",
+		"character varying(50)":       "string",
+		"numeric(10,2)":               "string",
+		"timestamp(3) with time zone": "time.Time",
+		"jsonb":                       "[]byte",
+		"integer[]":                   "string",
+		"character varying(20)[]":     "string",
+		"ARRAY":                       "string",
+		"tsvector":                    "string",
+	}
+	for pg, want := range tests {
+		if got := For(pg); got != want {
+			t.Errorf("For(%q) = %q, want %q", pg, got, want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Saba101/GoMetaSync/internal/policy"
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultLockTimeout is used by Apply when the policy doesn't set one, so a step waiting
+// on a busy table fails fast instead of queueing every other query behind it.
+const DefaultLockTimeout = 5 * time.Second
+
+// Blocked returns why p refuses s, or "" if s may run. Steps without SQL, or whose SQL is
+// only a guess, are always refused.
+func Blocked(p policy.Policy, s Step) string {
+	if strings.TrimSpace(s.SQL) == "" {
+		return "no SQL for this step"
+	}
+	if s.Guess {
+		return "needs review: " + s.Note
+	}
+	return p.Blocked(s.Kind)
+}
+
+// Apply runs steps, all of one database, on dsn. Consecutive steps that can run in a transaction
+// share one; a failing step rolls back its transaction, but batches committed before it stay applied.
+// Progress is written to log.
+func Apply(ctx context.Context, dsn string, steps []Step, p policy.Policy, log io.Writer) error {
+	for _, s := range steps {
+		if why := Blocked(p, s); why != "" {
+			return fmt.Errorf("migrate: %s %s: blocked: %s", s.Kind, s.Path, why)
+		}
+	}
+
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return fmt.Errorf("migrate: connect: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	lock := p.LockTimeout
+	if lock == 0 {
+		lock = DefaultLockTimeout
+	}
+	// session settings, so they also cover the steps run outside a transaction
+	if _, err := conn.Exec(ctx, fmt.Sprintf("SET lock_timeout = %d", lock.Milliseconds())); err != nil {
+		return fmt.Errorf("migrate: set lock_timeout: %w", err)
+	}
+	if _, err := conn.Exec(ctx, fmt.Sprintf("SET statement_timeout = %d", p.StatementTimeout.Milliseconds())); err != nil {
+		return fmt.Errorf("migrate: set statement_timeout: %w", err)
+	}
+
+	for i := 0; i < len(steps); {
+		if steps[i].NoTx {
+			if err := run(ctx, conn, steps[i], log); err != nil {
+				return err
+			}
+			i++
+			continue
+		}
+		j := i
+		for j < len(steps) && !steps[j].NoTx {
+			j++
+		}
+		if err := runTx(ctx, conn, steps[i:j], log); err != nil {
+			return err
+		}
+		i = j
+	}
+	return nil
+}
+
+func runTx(ctx context.Context, conn *pgx.Conn, steps []Step, log io.Writer) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate: begin transaction: %w", err)
+	}
+	// no-op after Commit
+	defer tx.Rollback(ctx)
+
+	for _, s := range steps {
+		fmt.Fprintf(log, "▶️ %s %s\n", s.Kind, s.Path)
+		if _, err := tx.Exec(ctx, s.SQL); err != nil {
+			return fmt.Errorf("migrate: %s %s: %w (transaction rolled back)", s.Kind, s.Path, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("migrate: commit: %w", err)
+	}
+	return nil
+}
+
+func run(ctx context.Context, conn *pgx.Conn, s Step, log io.Writer) error {
+	fmt.Fprintf(log, "▶️ %s %s (outside a transaction)\n", s.Kind, s.Path)
+	if _, err := conn.Exec(ctx, s.SQL); err != nil {
+		return fmt.Errorf("migrate: %s %s: %w", s.Kind, s.Path, err)
+	}
+	return nil
+}
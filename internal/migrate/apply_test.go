@@ -0,0 +1,29 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/policy"
+)
+
+func TestBlocked(t *testing.T) {
+	drop := Step{Kind: "column_dropped", SQL: "ALTER TABLE users DROP COLUMN email;"}
+	add := Step{Kind: "column_added", SQL: "ALTER TABLE users ADD COLUMN nick text;"}
+
+	tests := []struct {
+		name   string
+		policy policy.Policy
+		step   Step
+		want   string
+	}{
+		{"no policy", policy.Policy{}, drop, ""},
+		{"denied", policy.Policy{Deny: []string{"*_dropped"}}, drop, "denied by *_dropped"},
+		{"no SQL", policy.Policy{}, Step{Kind: "column_added", SQL: "  "}, "no SQL for this step"},
+		{"guess", policy.Policy{}, Step{Kind: "column_added", SQL: add.SQL, Guess: true, Note: "TODO: fix the type"}, "needs review: TODO: fix the type"},
+	}
+	for _, tt := range tests {
+		if got := Blocked(tt.policy, tt.step); got != tt.want {
+			t.Errorf("%s: Blocked = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/snapshot"
+)
+
+// DDL returns the steps that create everything in snap from an empty database, in dependency order:
+// schemas, tables, constraints, indexes, then foreign keys. Step kinds are object types, e.g. "table".
+//
+// It is limited to what a snapshot records: views are skipped, and column defaults, identity columns,
+// sequences, functions, triggers and privileges aren't part of the script.
+func DDL(snap *models.Snapshot, opts Options) []Step {
+	empty := &models.Snapshot{Databases: map[string]models.DatabaseSnapshot{}}
+	tables := withoutViews(snap)
+	changes := snapshot.Compare(empty, tables, snapshot.DiffOptions{})
+	steps := Plan(empty, tables, changes, opts)
+	for i := range steps {
+		steps[i].Kind = strings.TrimSuffix(steps[i].Kind, "_"+string(snapshot.Added))
+	}
+	return steps
+}
+
+// withoutViews returns a copy of snap without its views.
+func withoutViews(snap *models.Snapshot) *models.Snapshot {
+	out := *snap
+	out.Databases = map[string]models.DatabaseSnapshot{}
+	for name, db := range snap.Databases {
+		schemas := map[string]models.SchemaSnapshot{}
+		for sn, s := range db.Schemas {
+			tables := map[string]models.TableSnapshot{}
+			for tn, t := range s.Tables {
+				if !t.View {
+					tables[tn] = t
+				}
+			}
+			s.Tables = tables
+			schemas[sn] = s
+		}
+		db.Schemas = schemas
+		out.Databases[name] = db
+	}
+	return &out
+}
+
+// WriteObjectFiles writes each step to its own file under dir/<database>/, e.g.
+// "0003_table_public.users.sql". The number prefix keeps the files in execution order.
+func WriteObjectFiles(dir string, steps []Step) ([]string, error) {
+	var written []string
+	for _, db := range databases(steps) {
+		dbDir := filepath.Join(dir, db)
+		if err := os.MkdirAll(dbDir, 0o755); err != nil {
+			return written, err
+		}
+		for i, s := range stepsOf(steps, db) {
+			name := fmt.Sprintf("%04d_%s_%s.sql", i+1, s.Kind, fileSafe(strings.TrimPrefix(s.Path, db+".")))
+			path := filepath.Join(dbDir, name)
+			if err := os.WriteFile(path, []byte(script([]Step{s}, "")), 0o644); err != nil {
+				return written, err
+			}
+			written = append(written, path)
+		}
+	}
+	return written, nil
+}
+
+// fileSafe replaces characters that aren't safe in file names on every platform.
+func fileSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, s)
+}
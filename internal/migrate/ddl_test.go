@@ -0,0 +1,50 @@
+package migrate
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/models/modelstest"
+)
+
+func TestDDL(t *testing.T) {
+	users := models.TableSnapshot{
+		Name:             "users",
+		Columns:          map[string]string{"id": "integer", "email": "text"},
+		ColumnPositions:  map[string]int{"id": 1, "email": 2},
+		PrimaryKey:       []string{"id"},
+		CheckConstraints: map[string]string{"2200_16386_1_not_null": "id IS NOT NULL"},
+		Indexes: map[string]models.Index{
+			"users_pkey":      {Name: "users_pkey", Columns: []string{"id"}, Unique: true, Definition: "CREATE UNIQUE INDEX users_pkey ON public.users USING btree (id)"},
+			"users_email_idx": {Name: "users_email_idx", Columns: []string{"email"}, Definition: "CREATE INDEX users_email_idx ON public.users USING btree (email)"},
+		},
+	}
+	orders := models.TableSnapshot{
+		Name:    "orders",
+		Columns: map[string]string{"id": "integer", "user_id": "integer"},
+		ForeignKeys: map[string]models.ForeignKey{"orders_user_id_fkey": {
+			Name: "orders_user_id_fkey", Columns: []string{"user_id"},
+			RefSchema: "public", RefTable: "users", RefColumns: []string{"id"},
+		}},
+	}
+	activeUsers := models.TableSnapshot{Name: "active_users", View: true, Columns: map[string]string{"id": "integer"}}
+
+	var got []string
+	for _, s := range DDL(modelstest.Snapshot(users, orders, activeUsers), Options{}) {
+		got = append(got, s.Kind+" "+s.Path)
+	}
+	want := []string{
+		"schema app.public",
+		"table app.public.orders",
+		"table app.public.users",
+		"primary_key app.public.users",
+		"check app.public.users.2200_16386_1_not_null",
+		"index app.public.users.users_email_idx",
+		"foreign_key app.public.orders.orders_user_id_fkey",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("steps:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,380 @@
+// Package migrate turns the changes between two snapshots into PostgreSQL DDL.
+package migrate
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/snapshot"
+	"github.com/Saba101/GoMetaSync/internal/typecompat"
+)
+
+// Options controls Plan.
+type Options struct {
+	// Concurrently creates and drops indexes with CONCURRENTLY; those steps can't run in a transaction.
+	Concurrently bool
+	// Down marks the plan as undoing an up migration (built from snapshot.Invert), so steps that
+	// recreate what the up migration destroyed are annotated as lossy.
+	Down bool
+}
+
+// Step is one DDL statement of a migration.
+type Step struct {
+	DB     string
+	Kind   string // change kind the step implements, e.g. "column_dropped"
+	Path   string // object path, as snapshot.Change.Path
+	SQL    string // empty when the step can't be expressed; see Note
+	Danger string // why the step can destroy data; empty if it can't
+	Note   string // anything a reviewer should know
+	Lossy  string // down steps only: what the up migration destroyed that this step can't bring back
+	NoTx   bool   // must run outside a transaction block
+	Guess  bool   // the SQL is a best guess a person must check (see Note); Apply refuses it
+
+	phase phase
+}
+
+// phase orders steps so that every statement's dependencies exist when it runs:
+// things are dropped before the names they free are reused, tables exist before
+// their constraints, and foreign keys come last, once every referenced key exists.
+type phase int
+
+const (
+	phaseRenameTables phase = iota
+	phaseDropForeignKeys
+	phaseDropIndexes
+	phaseDropConstraints
+	phaseDropColumns
+	phaseDropTables
+	phaseDropSchemas
+	phaseCreateSchemas
+	phaseRenames
+	phaseCreateTables
+	phaseAddColumns
+	phaseAlterColumns
+	phaseAddConstraints
+	phaseCreateIndexes
+	phaseAddForeignKeys
+)
+
+// Plan returns the ordered steps that turn the old side of changes into the new side.
+// The snapshots are used to look up the tables changes belong to, e.g. to find constraint-backed indexes.
+func Plan(oldSnap, newSnap *models.Snapshot, changes []snapshot.Change, opts Options) []Step {
+	p := planner{old: oldSnap, new: newSnap, opts: opts, oldTables: map[string]string{}}
+	for _, c := range changes {
+		if c.Object == snapshot.ObjectTable && c.Action == snapshot.Renamed {
+			p.oldTables[c.DB+"."+c.Schema+"."+c.Table] = c.OldName
+		}
+	}
+
+	// columns of new tables are part of CREATE TABLE
+	created := map[string]bool{}
+	for _, c := range changes {
+		if c.Object == snapshot.ObjectTable && c.Action == snapshot.Added {
+			created[c.DB+"."+c.Schema+"."+c.Table] = true
+		}
+	}
+	for _, c := range changes {
+		if c.Object == snapshot.ObjectColumn && c.Action == snapshot.Added && created[c.DB+"."+c.Schema+"."+c.Table] {
+			continue
+		}
+		p.change(c)
+	}
+
+	sort.SliceStable(p.steps, func(i, j int) bool {
+		a, b := p.steps[i], p.steps[j]
+		if a.DB != b.DB {
+			return a.DB < b.DB
+		}
+		if a.phase != b.phase {
+			return a.phase < b.phase
+		}
+		return a.Path < b.Path
+	})
+	return p.steps
+}
+
+type planner struct {
+	old, new  *models.Snapshot
+	opts      Options
+	oldTables map[string]string // db.schema.table -> name in the old snapshot, for renamed tables
+	steps     []Step
+}
+
+// oldTable looks up the table of c in the old snapshot, following table renames.
+func (p *planner) oldTable(c snapshot.Change) (models.TableSnapshot, bool) {
+	name := c.Table
+	if old, ok := p.oldTables[c.DB+"."+c.Schema+"."+c.Table]; ok {
+		name = old
+	}
+	return lookupTable(p.old, c.DB, c.Schema, name)
+}
+
+func (p *planner) add(c snapshot.Change, ph phase, sql string) *Step {
+	p.steps = append(p.steps, Step{DB: c.DB, Kind: c.Kind(), Path: c.Path(), SQL: sql, phase: ph})
+	return &p.steps[len(p.steps)-1]
+}
+
+// lossy annotates a down step that can't undo its up step losslessly.
+func (p *planner) lossy(s *Step, why string) {
+	if p.opts.Down {
+		s.Lossy = why
+	}
+}
+
+func (p *planner) change(c snapshot.Change) {
+	tbl := qualify(c.Schema, c.Table)
+
+	switch c.Object {
+	case snapshot.ObjectDatabase:
+		if c.Action == snapshot.Dropped {
+			s := p.add(c, phaseDropSchemas, "")
+			s.Danger = "drops the database and everything in it"
+			s.Note = fmt.Sprintf("DROP DATABASE can't run inside the database itself; run it by hand from another one: DROP DATABASE %s;", quoteIdent(c.DB))
+		}
+
+	case snapshot.ObjectSchema:
+		switch c.Action {
+		case snapshot.Added:
+			s := p.add(c, phaseCreateSchemas, fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s;", quoteIdent(c.Schema)))
+			p.lossy(s, "recreates the schema structure only; the data dropped with it is gone")
+		case snapshot.Dropped:
+			s := p.add(c, phaseDropSchemas, fmt.Sprintf("DROP SCHEMA %s CASCADE;", quoteIdent(c.Schema)))
+			s.Danger = "drops the schema and every object and row in it"
+		}
+
+	case snapshot.ObjectTable:
+		switch c.Action {
+		case snapshot.Added:
+			t, _ := c.New.(models.TableSnapshot)
+			s := p.createTable(c, t)
+			p.lossy(s, "recreates the table empty; its dropped rows can't be restored")
+		case snapshot.Dropped:
+			s := p.add(c, phaseDropTables, fmt.Sprintf("DROP TABLE %s;", tbl))
+			s.Danger = "drops the table and all its rows"
+		case snapshot.Renamed:
+			s := p.add(c, phaseRenameTables, fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", qualify(c.Schema, c.OldName), quoteIdent(c.Table)))
+			s.Note, s.Guess = renameNote(c)
+		}
+
+	case snapshot.ObjectColumn:
+		switch c.Action {
+		case snapshot.Added:
+			s := p.columnStep(c, phaseAddColumns, "ALTER TABLE %s ADD COLUMN %s %s;", c.New)
+			p.lossy(s, "recreates the column with NULLs; its dropped data can't be restored")
+		case snapshot.Dropped:
+			s := p.add(c, phaseDropColumns, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", tbl, quoteIdent(c.Name)))
+			s.Danger = "drops the column and its data"
+		case snapshot.Changed:
+			if tc := c.TypeChange; tc != nil && tc.Class == typecompat.Widening {
+				s := p.columnStep(c, phaseAlterColumns, "ALTER TABLE %s ALTER COLUMN %s TYPE %s;", c.New)
+				s.Note = "widening: " + tc.Reason
+				break
+			}
+			s := p.columnStep(c, phaseAlterColumns, "ALTER TABLE %s ALTER COLUMN %[2]s TYPE %[3]s USING %[2]s::%[3]s;", c.New)
+			s.Danger = typeDanger(c)
+			p.lossy(s, "values converted by the up migration may not convert back exactly")
+		case snapshot.Renamed:
+			s := p.add(c, phaseRenames, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;", tbl, quoteIdent(c.OldName), quoteIdent(c.Name)))
+			s.Note, s.Guess = renameNote(c)
+		}
+
+	case snapshot.ObjectPrimaryKey:
+		if c.Action == snapshot.Dropped || c.Action == snapshot.Changed {
+			p.add(c, phaseDropConstraints, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", tbl, quoteIdent(p.pkName(c))))
+		}
+		if c.Action == snapshot.Added || c.Action == snapshot.Changed {
+			cols, _ := c.New.([]string)
+			p.add(c, phaseAddConstraints, fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s);", tbl, quoteList(cols)))
+		}
+
+	case snapshot.ObjectUnique, snapshot.ObjectCheck, snapshot.ObjectForeignKey:
+		p.constraint(c)
+
+	case snapshot.ObjectIndex:
+		p.index(c)
+	}
+}
+
+func (p *planner) createTable(c snapshot.Change, t models.TableSnapshot) *Step {
+	cols := t.OrderedColumns()
+	defs := make([]string, 0, len(cols))
+	var unknown []string
+	for _, col := range cols {
+		typ, ok := sqlType(t.ColumnType(col))
+		if !ok {
+			unknown = append(unknown, col)
+		}
+		defs = append(defs, fmt.Sprintf("    %s %s", quoteIdent(col), typ))
+	}
+	sql := fmt.Sprintf("CREATE TABLE %s (\n%s\n);", qualify(c.Schema, c.Table), strings.Join(defs, ",\n"))
+	s := p.add(c, phaseCreateTables, sql)
+	if len(unknown) > 0 {
+		s.Guess = true
+		s.Note = fmt.Sprintf("TODO: the snapshot doesn't record the exact type of %s; fix before running", strings.Join(unknown, ", "))
+	}
+	return s
+}
+
+// columnStep adds a statement formatted with (table, column, type), or a TODO when the type is unknown.
+func (p *planner) columnStep(c snapshot.Change, ph phase, format string, dataType any) *Step {
+	dt, _ := dataType.(string)
+	typ, ok := sqlType(dt)
+	s := p.add(c, ph, fmt.Sprintf(format, qualify(c.Schema, c.Table), quoteIdent(c.Name), typ))
+	if !ok {
+		s.Guess = true
+		s.Note = fmt.Sprintf("TODO: the snapshot only records the type of %s as %q; fix before running", c.Name, dt)
+	}
+	return s
+}
+
+func (p *planner) constraint(c snapshot.Change) {
+	tbl := qualify(c.Schema, c.Table)
+	dropPhase, addPhase := phaseDropConstraints, phaseAddConstraints
+	if c.Object == snapshot.ObjectForeignKey {
+		dropPhase, addPhase = phaseDropForeignKeys, phaseAddForeignKeys
+	}
+
+	// NOT NULL shows up in information_schema as a check constraint named like 2200_16386_1_not_null
+	if c.Object == snapshot.ObjectCheck {
+		if col, ok := notNullColumn(c.Name, c.Old); ok && (c.Action == snapshot.Dropped || c.Action == snapshot.Changed) {
+			p.add(c, dropPhase, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL;", tbl, quoteIdent(col)))
+			c.Old = nil
+		}
+		if col, ok := notNullColumn(c.Name, c.New); ok && (c.Action == snapshot.Added || c.Action == snapshot.Changed) {
+			p.add(c, addPhase, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL;", tbl, quoteIdent(col)))
+			c.New = nil
+		}
+		if c.Old == nil && c.New == nil {
+			return
+		}
+	}
+
+	switch c.Action {
+	case snapshot.Renamed:
+		if _, ok := notNullColumn(c.OldName, c.Old); ok {
+			return // NOT NULL pseudo constraints are named after table OIDs; there is nothing to rename
+		}
+		s := p.add(c, phaseRenames, fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s TO %s;", tbl, quoteIdent(c.OldName), quoteIdent(c.Name)))
+		s.Note, s.Guess = renameNote(c)
+		return
+	case snapshot.Dropped, snapshot.Changed:
+		if c.Old != nil {
+			p.add(c, dropPhase, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", tbl, quoteIdent(c.Name)))
+		}
+	}
+	if (c.Action != snapshot.Added && c.Action != snapshot.Changed) || c.New == nil {
+		return
+	}
+
+	var def string
+	switch v := c.New.(type) {
+	case []string:
+		def = fmt.Sprintf("UNIQUE (%s)", quoteList(v))
+	case string:
+		def = fmt.Sprintf("CHECK (%s)", v)
+	case models.ForeignKey:
+		def = foreignKeyDef(v)
+	}
+	p.add(c, addPhase, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s;", tbl, quoteIdent(c.Name), def))
+}
+
+func (p *planner) index(c snapshot.Change) {
+	// indexes behind primary keys and unique constraints come and go with the constraint
+	if p.backsConstraint(c) {
+		return
+	}
+	concurrently := ""
+	if p.opts.Concurrently {
+		concurrently = "CONCURRENTLY "
+	}
+
+	switch c.Action {
+	case snapshot.Renamed:
+		s := p.add(c, phaseRenames, fmt.Sprintf("ALTER INDEX %s RENAME TO %s;", qualify(c.Schema, c.OldName), quoteIdent(c.Name)))
+		s.Note, s.Guess = renameNote(c)
+		return
+	case snapshot.Dropped, snapshot.Changed:
+		s := p.add(c, phaseDropIndexes, fmt.Sprintf("DROP INDEX %s%s;", concurrently, qualify(c.Schema, c.Name)))
+		s.NoTx = p.opts.Concurrently
+	}
+	if c.Action == snapshot.Added || c.Action == snapshot.Changed {
+		idx, _ := c.New.(models.Index)
+		s := p.add(c, phaseCreateIndexes, createIndexSQL(idx.Definition, p.opts.Concurrently)+";")
+		s.NoTx = p.opts.Concurrently
+	}
+}
+
+// backsConstraint reports whether the index of c implements a primary key or unique constraint on either side.
+func (p *planner) backsConstraint(c snapshot.Change) bool {
+	oldName := c.Name
+	if c.Action == snapshot.Renamed {
+		oldName = c.OldName
+	}
+	if t, ok := p.oldTable(c); ok && backs(t, oldName, c.Old) {
+		return true
+	}
+	t, ok := lookupTable(p.new, c.DB, c.Schema, c.Table)
+	return ok && backs(t, c.Name, c.New)
+}
+
+func backs(t models.TableSnapshot, name string, val any) bool {
+	if _, ok := t.UniqueConstraints[name]; ok {
+		return true
+	}
+	idx, ok := val.(models.Index)
+	return ok && isPKIndex(t, idx)
+}
+
+// pkName is the primary key constraint's name: the name of the index backing it, or the Postgres default.
+func (p *planner) pkName(c snapshot.Change) string {
+	if t, ok := p.oldTable(c); ok {
+		for _, name := range slices.Sorted(maps.Keys(t.Indexes)) {
+			if isPKIndex(t, t.Indexes[name]) {
+				return name
+			}
+		}
+	}
+	return c.Table + "_pkey"
+}
+
+func isPKIndex(t models.TableSnapshot, idx models.Index) bool {
+	return idx.Unique && len(t.PrimaryKey) > 0 && slices.Equal(idx.Columns, t.PrimaryKey) && strings.HasSuffix(idx.Name, "_pkey")
+}
+
+func lookupTable(snap *models.Snapshot, db, schema, table string) (models.TableSnapshot, bool) {
+	if snap == nil {
+		return models.TableSnapshot{}, false
+	}
+	t, ok := snap.Databases[db].Schemas[schema].Tables[table]
+	return t, ok
+}
+
+// typeDanger explains what a column type change does to the table and its data.
+func typeDanger(c snapshot.Change) string {
+	tc := c.TypeChange
+	if tc == nil {
+		return fmt.Sprintf("changes the type from %v to %v; values may be rewritten, truncated or fail to convert", c.Old, c.New)
+	}
+	switch tc.Class {
+	case typecompat.RewriteRequired:
+		return fmt.Sprintf("%s: rewrites the table under an ACCESS EXCLUSIVE lock; %s", tc.Class, tc.Reason)
+	case typecompat.Narrowing:
+		return fmt.Sprintf("%s: rewrites the table and fails on values that don't fit; %s", tc.Class, tc.Reason)
+	}
+	return fmt.Sprintf("%s: %s", tc.Class, tc.Reason)
+}
+
+// renameNote explains how a rename was found, and whether it is only a guess.
+func renameNote(c snapshot.Change) (string, bool) {
+	switch {
+	case c.Confirmed:
+		return "", false
+	case c.ByDefinition:
+		return "rename matched by identical definition", false
+	}
+	return fmt.Sprintf("rename guessed with confidence %.2f; confirm it with a rename hint, or this may be a drop and an add", c.Confidence), true
+}
@@ -0,0 +1,179 @@
+package migrate
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/models/modelstest"
+	"github.com/Saba101/GoMetaSync/internal/policy"
+	"github.com/Saba101/GoMetaSync/internal/snapshot"
+)
+
+// users builds a users table from full column types; Columns holds them without modifiers, as information_schema does.
+func users(types map[string]string) models.TableSnapshot {
+	cols := map[string]string{}
+	for name, typ := range types {
+		base, _, _ := strings.Cut(typ, "(")
+		cols[name] = base
+	}
+	return models.TableSnapshot{Name: "users", Columns: cols, ColumnTypes: types, PrimaryKey: []string{"id"}}
+}
+
+func plan(oldSnap, newSnap *models.Snapshot, opts Options) []Step {
+	return Plan(oldSnap, newSnap, snapshot.Compare(oldSnap, newSnap, snapshot.DiffOptions{}), opts)
+}
+
+func TestPlanOrder(t *testing.T) {
+	oldSnap := modelstest.Snapshot(
+		users(map[string]string{"id": "integer", "legacy": "text"}),
+		models.TableSnapshot{Name: "tmp", Columns: map[string]string{"id": "integer"}},
+	)
+	orders := models.TableSnapshot{
+		Name:    "orders",
+		Columns: map[string]string{"id": "integer", "user_id": "integer"},
+		ForeignKeys: map[string]models.ForeignKey{"orders_user_id_fkey": {
+			Name: "orders_user_id_fkey", Columns: []string{"user_id"},
+			RefSchema: "public", RefTable: "users", RefColumns: []string{"id"},
+		}},
+		Indexes: map[string]models.Index{"orders_user_id_idx": {
+			Name: "orders_user_id_idx", Columns: []string{"user_id"},
+			Definition: "CREATE INDEX orders_user_id_idx ON public.orders USING btree (user_id)",
+		}},
+	}
+	newSnap := modelstest.Snapshot(users(map[string]string{"id": "integer", "email": "text"}), orders)
+
+	var got []string
+	for _, s := range plan(oldSnap, newSnap, Options{}) {
+		got = append(got, s.Kind+" "+s.Path)
+	}
+	want := []string{
+		"column_dropped app.public.users.legacy",
+		"table_dropped app.public.tmp",
+		"table_added app.public.orders",
+		"column_added app.public.users.email",
+		"index_added app.public.orders.orders_user_id_idx",
+		"foreign_key_added app.public.orders.orders_user_id_fkey",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("steps:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestPlanSteps(t *testing.T) {
+	tests := []struct {
+		name       string
+		old, new   map[string]string
+		wantSQL    string
+		wantDanger string
+		wantNote   string
+		wantGuess  bool
+	}{
+		{
+			name:       "drop column",
+			old:        map[string]string{"id": "integer", "email": "text"},
+			new:        map[string]string{"id": "integer"},
+			wantSQL:    "ALTER TABLE public.users DROP COLUMN email;",
+			wantDanger: "drops the column and its data",
+		},
+		{
+			name:       "quotes identifiers that need it",
+			old:        map[string]string{"id": "integer", "User Name": "text"},
+			new:        map[string]string{"id": "integer"},
+			wantSQL:    `ALTER TABLE public.users DROP COLUMN "User Name";`,
+			wantDanger: "drops the column and its data",
+		},
+		{
+			name:     "widening has no USING",
+			old:      map[string]string{"id": "integer", "email": "character varying(50)"},
+			new:      map[string]string{"id": "integer", "email": "character varying(100)"},
+			wantSQL:  "ALTER TABLE public.users ALTER COLUMN email TYPE character varying(100);",
+			wantNote: "widening: ",
+		},
+		{
+			name:       "rewrite casts with USING",
+			old:        map[string]string{"id": "integer"},
+			new:        map[string]string{"id": "bigint"},
+			wantSQL:    "ALTER TABLE public.users ALTER COLUMN id TYPE bigint USING id::bigint;",
+			wantDanger: "rewrite-required: rewrites the table under an ACCESS EXCLUSIVE lock",
+		},
+		{
+			name:       "incompatible casts with USING",
+			old:        map[string]string{"id": "text"},
+			new:        map[string]string{"id": "integer"},
+			wantSQL:    "ALTER TABLE public.users ALTER COLUMN id TYPE integer USING id::integer;",
+			wantDanger: "incompatible",
+		},
+		{
+			name:      "unknown type is a TODO",
+			old:       map[string]string{"id": "integer"},
+			new:       map[string]string{"id": "integer", "tags": "ARRAY"},
+			wantSQL:   "ALTER TABLE public.users ADD COLUMN tags /* ARRAY */ text;",
+			wantNote:  "TODO: ",
+			wantGuess: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := plan(modelstest.Snapshot(users(tt.old)), modelstest.Snapshot(users(tt.new)), Options{})
+			if len(steps) != 1 {
+				t.Fatalf("got %d steps, want 1: %+v", len(steps), steps)
+			}
+			s := steps[0]
+			if s.SQL != tt.wantSQL {
+				t.Errorf("SQL = %s, want %s", s.SQL, tt.wantSQL)
+			}
+			if !strings.Contains(s.Danger, tt.wantDanger) || (tt.wantDanger == "") != (s.Danger == "") {
+				t.Errorf("Danger = %q, want %q", s.Danger, tt.wantDanger)
+			}
+			if !strings.HasPrefix(s.Note, tt.wantNote) || (tt.wantNote == "") != (s.Note == "") {
+				t.Errorf("Note = %q, want prefix %q", s.Note, tt.wantNote)
+			}
+			if s.Guess != tt.wantGuess {
+				t.Errorf("Guess = %v, want %v", s.Guess, tt.wantGuess)
+			}
+		})
+	}
+}
+
+func TestPlanConcurrently(t *testing.T) {
+	idx := models.Index{Name: "users_email_idx", Columns: []string{"email"}, Definition: "CREATE INDEX users_email_idx ON public.users USING btree (email)"}
+	oldT := users(map[string]string{"id": "integer", "email": "text"})
+	newT := oldT
+	newT.Indexes = map[string]models.Index{idx.Name: idx}
+
+	steps := plan(modelstest.Snapshot(oldT), modelstest.Snapshot(newT), Options{Concurrently: true})
+	if len(steps) != 1 || !steps[0].NoTx || !strings.Contains(steps[0].SQL, "CREATE INDEX CONCURRENTLY users_email_idx") {
+		t.Errorf("steps = %+v, want one CREATE INDEX CONCURRENTLY outside a transaction", steps)
+	}
+}
+
+func TestPlanDown(t *testing.T) {
+	oldSnap := modelstest.Snapshot(users(map[string]string{"id": "integer", "email": "text"}))
+	newSnap := modelstest.Snapshot(users(map[string]string{"id": "integer"}))
+	down := snapshot.Invert(snapshot.Compare(oldSnap, newSnap, snapshot.DiffOptions{}))
+
+	steps := Plan(newSnap, oldSnap, down, Options{Down: true})
+	if len(steps) != 1 {
+		t.Fatalf("got %d steps, want 1: %+v", len(steps), steps)
+	}
+	s := steps[0]
+	if s.SQL != "ALTER TABLE public.users ADD COLUMN email text;" || !strings.Contains(s.Lossy, "can't be restored") {
+		t.Errorf("step = %+v, want a lossy ADD COLUMN email", s)
+	}
+	if up := Plan(newSnap, oldSnap, down, Options{}); up[0].Lossy != "" {
+		t.Errorf("Lossy = %q without Options.Down", up[0].Lossy)
+	}
+}
+
+func TestPlanDroppedDatabase(t *testing.T) {
+	changes := []snapshot.Change{{Action: snapshot.Dropped, Object: snapshot.ObjectDatabase, DB: "crm"}}
+	steps := Plan(nil, nil, changes, Options{})
+	if len(steps) != 1 || steps[0].SQL != "" || !strings.Contains(steps[0].Note, "DROP DATABASE crm;") {
+		t.Fatalf("steps = %+v, want one manual DROP DATABASE step", steps)
+	}
+	if why := Blocked(policy.Policy{}, steps[0]); why != "no SQL for this step" {
+		t.Errorf("Blocked = %q, want apply to refuse it", why)
+	}
+}
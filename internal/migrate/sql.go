@@ -0,0 +1,88 @@
+package migrate
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/snapshot"
+)
+
+// reserved are keywords that can't be used as bare identifiers (the reserved ones from the Postgres docs).
+var reserved = []string{
+	"all", "analyse", "analyze", "and", "any", "array", "as", "asc", "asymmetric", "both", "case", "cast",
+	"check", "collate", "column", "constraint", "create", "current_catalog", "current_date", "current_role",
+	"current_time", "current_timestamp", "current_user", "default", "deferrable", "desc", "distinct", "do",
+	"else", "end", "except", "false", "fetch", "for", "foreign", "from", "grant", "group", "having", "in",
+	"initially", "intersect", "into", "lateral", "leading", "limit", "localtime", "localtimestamp", "not",
+	"null", "offset", "on", "only", "or", "order", "placing", "primary", "references", "returning", "select",
+	"session_user", "some", "symmetric", "table", "then", "to", "trailing", "true", "union", "unique", "user",
+	"using", "variadic", "when", "where", "window", "with",
+}
+
+var plainIdentRe = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// quoteIdent double-quotes an identifier unless it can be written bare.
+func quoteIdent(s string) string {
+	if plainIdentRe.MatchString(s) && !slices.Contains(reserved, s) {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func qualify(schema, name string) string {
+	return quoteIdent(schema) + "." + quoteIdent(name)
+}
+
+func quoteList(cols []string) string {
+	out := make([]string, len(cols))
+	for i, c := range cols {
+		out[i] = quoteIdent(c)
+	}
+	return strings.Join(out, ", ")
+}
+
+// sqlType returns the DDL spelling of an information_schema data_type.
+// ARRAY and USER-DEFINED don't say which type they are, so they can't be written back.
+func sqlType(dataType string) (string, bool) {
+	switch dataType {
+	case "ARRAY", "USER-DEFINED", "":
+		return fmt.Sprintf("/* %s */ text", dataType), false
+	}
+	return dataType, true
+}
+
+func foreignKeyDef(fk models.ForeignKey) string {
+	def := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)",
+		quoteList(fk.Columns), qualify(fk.RefSchema, fk.RefTable), quoteList(fk.RefColumns))
+	if fk.UpdateRule != "" && fk.UpdateRule != "NO ACTION" {
+		def += " ON UPDATE " + fk.UpdateRule
+	}
+	if fk.DeleteRule != "" && fk.DeleteRule != "NO ACTION" {
+		def += " ON DELETE " + fk.DeleteRule
+	}
+	return def
+}
+
+var createIndexRe = regexp.MustCompile(`(?i)^\s*CREATE\s+(UNIQUE\s+)?INDEX\s+`)
+
+// createIndexSQL returns an indexdef, optionally as CREATE INDEX CONCURRENTLY.
+func createIndexSQL(def string, concurrently bool) string {
+	if !concurrently {
+		return def
+	}
+	return createIndexRe.ReplaceAllStringFunc(def, func(m string) string {
+		return strings.TrimRight(m, " \t") + " CONCURRENTLY "
+	})
+}
+
+// notNullColumn recognizes the pseudo check constraints information_schema reports for NOT NULL columns.
+func notNullColumn(name string, clause any) (string, bool) {
+	s, ok := clause.(string)
+	if !ok {
+		return "", false
+	}
+	return snapshot.NotNullColumn(name, s)
+}
@@ -0,0 +1,163 @@
+package migrate
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Write renders steps as a SQL script, one section per database.
+// Destructive steps are preceded by a "-- DANGER" comment.
+func Write(w io.Writer, steps []Step) error {
+	for i, db := range databases(steps) {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "-- ========== database: %s ==========\n", db)
+		if err := writeSteps(w, stepsOf(steps, db)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSteps(w io.Writer, steps []Step) error {
+	for _, s := range steps {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "-- %s %s\n", s.Kind, s.Path)
+		if s.Danger != "" {
+			fmt.Fprintf(w, "-- DANGER: %s\n", s.Danger)
+		}
+		if s.Lossy != "" {
+			fmt.Fprintf(w, "-- LOSSY: %s\n", s.Lossy)
+		}
+		if s.Note != "" {
+			fmt.Fprintf(w, "-- NOTE: %s\n", s.Note)
+		}
+		if s.NoTx {
+			fmt.Fprintln(w, "-- must run outside a transaction block")
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimSpace(s.SQL)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Layout is the on-disk migration format written by WriteFiles.
+type Layout string
+
+const (
+	LayoutGolangMigrate Layout = "golang-migrate" // {version}_{name}.up.sql + {version}_{name}.down.sql
+	LayoutGoose         Layout = "goose"          // {version}_{name}.sql with -- +goose Up / Down sections
+	LayoutAtlas         Layout = "atlas"          // {version}_{name}.sql; down scripts in down/ are a gometasync convention
+)
+
+// Layouts lists the supported layouts.
+var Layouts = []Layout{LayoutGolangMigrate, LayoutGoose, LayoutAtlas}
+
+// WriteFiles writes paired up/down migrations into dir/<database>/ in the given layout and returns
+// the files written. Files are versioned with the UTC timestamp now, as all three tools accept.
+//
+// golang-migrate's postgres driver runs a file as one multi-statement Exec, i.e. one implicit
+// transaction, so steps that must run outside a transaction (Options.Concurrently) are rejected
+// for that layout. goose and atlas files get their tool's no-transaction directive instead.
+func WriteFiles(dir string, layout Layout, name string, now time.Time, up, down []Step) ([]string, error) {
+	if !slices.Contains(Layouts, layout) {
+		return nil, fmt.Errorf("migrate: unknown layout %q", layout)
+	}
+	if layout == LayoutGolangMigrate && (needsNoTx(up) || needsNoTx(down)) {
+		return nil, fmt.Errorf("migrate: %s runs each file in one transaction, which CONCURRENTLY statements can't run in; use the goose or atlas layout, or drop --concurrently", layout)
+	}
+	base := now.UTC().Format("20060102150405") + "_" + name
+
+	var written []string
+	for _, db := range databases(append(slices.Clone(up), down...)) {
+		dbUp, dbDown := stepsOf(up, db), stepsOf(down, db)
+		dbDir := filepath.Join(dir, db)
+
+		files := map[string]string{}
+		switch layout {
+		case LayoutGolangMigrate:
+			files[base+".up.sql"] = script(dbUp, "")
+			files[base+".down.sql"] = script(dbDown, "")
+		case LayoutGoose:
+			var b strings.Builder
+			if needsNoTx(dbUp) || needsNoTx(dbDown) {
+				b.WriteString("-- +goose NO TRANSACTION\n")
+			}
+			b.WriteString(script(dbUp, "-- +goose Up"))
+			b.WriteString("\n")
+			b.WriteString(script(dbDown, "-- +goose Down"))
+			files[base+".sql"] = b.String()
+		case LayoutAtlas:
+			// atlas only versions up scripts; run `atlas migrate hash` after adding files.
+			// down/ isn't read by atlas: it keeps the rollback script next to its migration.
+			header := ""
+			if needsNoTx(dbUp) {
+				header = "-- atlas:txmode none"
+			}
+			files[base+".sql"] = script(dbUp, header)
+			files[filepath.Join("down", base+".sql")] = script(dbDown, "")
+		}
+
+		for _, rel := range slices.Sorted(maps.Keys(files)) {
+			path := filepath.Join(dbDir, rel)
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return written, err
+			}
+			if err := os.WriteFile(path, []byte(files[rel]), 0o644); err != nil {
+				return written, err
+			}
+			written = append(written, path)
+		}
+	}
+	return written, nil
+}
+
+// script renders steps under an optional first line.
+func script(steps []Step, header string) string {
+	var b strings.Builder
+	if header != "" {
+		b.WriteString(header + "\n")
+	}
+	b.WriteString("-- Generated by GoMetaSync.\n")
+	if needsNoTx(steps) {
+		b.WriteString("-- Contains statements that can't run in a transaction; run them one at a time.\n")
+	}
+	if len(steps) == 0 {
+		b.WriteString("-- (no changes)\n")
+	}
+	_ = writeSteps(&b, steps)
+	return b.String()
+}
+
+func needsNoTx(steps []Step) bool {
+	return slices.ContainsFunc(steps, func(s Step) bool { return s.NoTx })
+}
+
+// databases returns the databases steps touch, in order of first appearance.
+func databases(steps []Step) []string {
+	var dbs []string
+	for _, s := range steps {
+		if !slices.Contains(dbs, s.DB) {
+			dbs = append(dbs, s.DB)
+		}
+	}
+	return dbs
+}
+
+func stepsOf(steps []Step, db string) []Step {
+	var out []Step
+	for _, s := range steps {
+		if s.DB == db {
+			out = append(out, s)
+		}
+	}
+	return out
+}
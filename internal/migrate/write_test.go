@@ -0,0 +1,79 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteFiles(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	up := []Step{{DB: "app", Kind: "index_added", Path: "app.public.users.users_email_idx", SQL: "CREATE INDEX users_email_idx ON users (email);"}}
+	down := []Step{{DB: "app", Kind: "index_dropped", Path: "app.public.users.users_email_idx", SQL: "DROP INDEX users_email_idx;"}}
+	upNoTx := []Step{{DB: "app", Kind: "index_added", Path: "app.public.users.users_email_idx", SQL: "CREATE INDEX CONCURRENTLY users_email_idx ON users (email);", NoTx: true}}
+
+	tests := []struct {
+		layout  Layout
+		up      []Step
+		want    map[string][]string // file → substrings it must contain
+		wantErr bool
+	}{
+		{LayoutGolangMigrate, up, map[string][]string{
+			"app/20240501123000_sync.up.sql":   {"CREATE INDEX users_email_idx"},
+			"app/20240501123000_sync.down.sql": {"DROP INDEX users_email_idx"},
+		}, false},
+		{LayoutGolangMigrate, upNoTx, nil, true},
+		{LayoutGoose, upNoTx, map[string][]string{
+			"app/20240501123000_sync.sql": {"-- +goose NO TRANSACTION\n-- +goose Up", "CONCURRENTLY", "-- +goose Down", "DROP INDEX"},
+		}, false},
+		{LayoutAtlas, upNoTx, map[string][]string{
+			"app/20240501123000_sync.sql":      {"-- atlas:txmode none\n", "CONCURRENTLY"},
+			"app/down/20240501123000_sync.sql": {"DROP INDEX"},
+		}, false},
+		{LayoutAtlas, up, map[string][]string{
+			"app/20240501123000_sync.sql": {"CREATE INDEX users_email_idx"},
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.layout), func(t *testing.T) {
+			dir := t.TempDir()
+			files, err := WriteFiles(dir, tt.layout, "sync", now, tt.up, down)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("want an error, wrote %v", files)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) < len(tt.want) {
+				t.Errorf("wrote %v, want %d files", files, len(tt.want))
+			}
+			for rel, subs := range tt.want {
+				b, err := os.ReadFile(filepath.Join(dir, rel))
+				if err != nil {
+					t.Error(err)
+					continue
+				}
+				for _, sub := range subs {
+					if !strings.Contains(string(b), sub) {
+						t.Errorf("%s doesn't contain %q:\n%s", rel, sub, b)
+					}
+				}
+			}
+			if tt.layout == LayoutAtlas && !tt.up[0].NoTx {
+				b, _ := os.ReadFile(filepath.Join(dir, "app/20240501123000_sync.sql"))
+				if strings.Contains(string(b), "txmode") {
+					t.Errorf("txmode directive without CONCURRENTLY steps:\n%s", b)
+				}
+			}
+		})
+	}
+
+	if _, err := WriteFiles(t.TempDir(), "flyway", "sync", now, up, down); err == nil {
+		t.Error("unknown layout: want an error")
+	}
+}
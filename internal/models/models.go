@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"time"
+)
 
 type Snapshot struct {
 	Timestamp time.Time                  `json:"timestamp"`
@@ -11,6 +16,11 @@ type Snapshot struct {
 type DatabaseSnapshot struct {
 	DBName  string                    `json:"db_name"`
 	Schemas map[string]SchemaSnapshot `json:"schemas"`
+
+	// Capture point of the repeatable-read transaction the catalog was read in
+	TxSnapshot       string `json:"tx_snapshot,omitempty"`       // txid_current_snapshot(): xmin:xmax:xip_list
+	LSN              string `json:"lsn,omitempty"`               // WAL position (replay LSN on standbys)
+	ExportedSnapshot string `json:"exported_snapshot,omitempty"` // pg_export_snapshot() id, only valid while collecting
 }
 
 type SchemaSnapshot struct {
@@ -21,6 +31,14 @@ type SchemaSnapshot struct {
 type TableSnapshot struct {
 	Name    string            `json:"name"`
 	Columns map[string]string `json:"columns"` // col_name: data_type
+	// View is set for views, whose columns information_schema lists like a table's.
+	// Absent in snapshots taken before it was collected.
+	View bool `json:"view,omitempty"`
+	// col_name: full type with modifiers, format_type(), e.g. "character varying(50)", "integer[]".
+	// Absent in snapshots taken before it was collected.
+	ColumnTypes map[string]string `json:"column_types,omitempty"`
+	// col_name: ordinal position (attnum; dropped columns leave gaps). Absent in older snapshots.
+	ColumnPositions map[string]int `json:"column_positions,omitempty"`
 
 	// NEW
 	PrimaryKey       []string                     `json:"primary_key,omitempty"` // ordered PK columns
@@ -46,3 +64,27 @@ type Index struct {
 	Unique     bool     `json:"unique"`
 	Definition string   `json:"definition"`        // full indexdef text
 }
+
+// ColumnType returns the full type of a column, or its data_type when the snapshot has no full type.
+func (t TableSnapshot) ColumnType(col string) string {
+	if full, ok := t.ColumnTypes[col]; ok {
+		return full
+	}
+	return t.Columns[col]
+}
+
+// OrderedColumns returns the column names in table order. Columns without a recorded position
+// come last, by name, so snapshots taken before positions were collected sort alphabetically.
+func (t TableSnapshot) OrderedColumns() []string {
+	return slices.SortedFunc(maps.Keys(t.Columns), func(a, b string) int {
+		pa, aok := t.ColumnPositions[a]
+		pb, bok := t.ColumnPositions[b]
+		if aok != bok {
+			if aok {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(pa, pb), cmp.Compare(a, b))
+	})
+}
@@ -0,0 +1,26 @@
+// Package modelstest builds small snapshots for tests.
+package modelstest
+
+import "github.com/Saba101/GoMetaSync/internal/models"
+
+// Snapshot returns a snapshot of one database, "app", with one schema, "public", holding tables.
+func Snapshot(tables ...models.TableSnapshot) *models.Snapshot {
+	ts := map[string]models.TableSnapshot{}
+	for _, t := range tables {
+		ts[t.Name] = t
+	}
+	return &models.Snapshot{Databases: map[string]models.DatabaseSnapshot{
+		"app": {DBName: "app", Schemas: map[string]models.SchemaSnapshot{
+			"public": {Name: "public", Tables: ts},
+		}},
+	}}
+}
+
+// Table returns a table whose columns are all text.
+func Table(name string, cols ...string) models.TableSnapshot {
+	t := models.TableSnapshot{Name: name, Columns: map[string]string{}}
+	for _, c := range cols {
+		t.Columns[c] = "text"
+	}
+	return t
+}
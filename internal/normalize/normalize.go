@@ -0,0 +1,271 @@
+// Package normalize canonicalizes SQL expressions stored in snapshots (check clauses, index
+// definitions) so that text differing only in formatting between Postgres versions compares equal.
+package normalize
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// textTypes are casts that Postgres versions add or drop freely around text-like values,
+// e.g. 'a'::text vs 'a'::character varying. Without a length modifier they don't change what a
+// constraint accepts; character(n) and varchar(n) truncate, so those casts are kept. Bare
+// "character" is character(1) and is kept too.
+var textTypes = [][]string{
+	{"text"},
+	{"character", "varying"},
+	{"varchar"},
+	{"bpchar"},
+	{"name"},
+}
+
+// droppedQualifiers are schema prefixes removed from identifiers; they depend on search_path, not on the schema.
+var droppedQualifiers = []string{"pg_catalog.", "public."}
+
+// Check normalizes a check constraint clause, e.g. "((status)::text = 'a'::text)" → "status = 'a'".
+func Check(clause string) string {
+	return render(simplify(tokenize(clause)))
+}
+
+// IndexDef normalizes an indexdef, e.g.
+// "CREATE INDEX i ON public.t USING btree (lower((email)::text))" → "create index i on t using btree(lower(email))".
+func IndexDef(def string) string {
+	return render(simplify(tokenize(def)))
+}
+
+// ---------- tokens ----------
+
+type kind int
+
+const (
+	word   kind = iota // identifier, keyword or number
+	quoted             // "quoted identifier"
+	str                // 'string literal'
+	punct              // ( ) [ ] ,
+	op                 // operators, including ::
+)
+
+type token struct {
+	kind kind
+	text string
+}
+
+func tokenize(s string) []token {
+	var toks []token
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '\'' || r == '"':
+			// quotes are escaped by doubling
+			j := i + 1
+			for j < len(rs) {
+				if rs[j] == r {
+					if j+1 < len(rs) && rs[j+1] == r {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			end := min(j+1, len(rs))
+			k := str
+			if r == '"' {
+				k = quoted
+			}
+			toks = append(toks, token{k, string(rs[i:end])})
+			i = end
+
+		case isWordRune(r):
+			j := i
+			for j < len(rs) && (isWordRune(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			// unquoted identifiers and keywords are case-insensitive
+			toks = append(toks, token{word, strings.ToLower(string(rs[i:j]))})
+			i = j
+
+		case strings.ContainsRune("()[],", r):
+			toks = append(toks, token{punct, string(r)})
+			i++
+
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && !isWordRune(rs[j]) && !strings.ContainsRune(`()[],'"`, rs[j]) {
+				j++
+			}
+			toks = append(toks, token{op, string(rs[i:j])})
+			i = j
+		}
+	}
+	return toks
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// ---------- rewriting ----------
+
+func simplify(toks []token) []token {
+	toks = dropQualifiers(toks)
+	toks = dropTextCasts(toks)
+	// removing parentheses can expose more of them, e.g. ((x)) → (x) → x
+	for {
+		n := len(toks)
+		toks = dropRedundantParens(toks)
+		if len(toks) == n {
+			return toks
+		}
+	}
+}
+
+func dropQualifiers(toks []token) []token {
+	for i, t := range toks {
+		if t.kind != word {
+			continue
+		}
+		for _, q := range droppedQualifiers {
+			if rest, ok := strings.CutPrefix(t.text, q); ok && rest != "" {
+				toks[i].text = rest
+			}
+		}
+	}
+	return toks
+}
+
+// dropTextCasts removes "::<text type>" and "::<text type>[]" when the type has no length modifier.
+func dropTextCasts(toks []token) []token {
+	var out []token
+	for i := 0; i < len(toks); i++ {
+		if toks[i].kind == op && toks[i].text == "::" {
+			if n := textCastLen(toks[i+1:]); n > 0 {
+				i += n
+				continue
+			}
+		}
+		out = append(out, toks[i])
+	}
+	return out
+}
+
+// textCastLen returns how many tokens at the start of toks spell a text type, or 0.
+func textCastLen(toks []token) int {
+	for _, tt := range textTypes {
+		if len(toks) < len(tt) {
+			continue
+		}
+		match := true
+		for i, w := range tt {
+			if toks[i].kind != word || toks[i].text != w {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		n := len(tt)
+		// character varying(n) truncates: not a text cast
+		if n < len(toks) && toks[n].text == "(" {
+			return 0
+		}
+		if n+1 < len(toks) && toks[n].text == "[" && toks[n+1].text == "]" {
+			n += 2
+		}
+		return n
+	}
+	return 0
+}
+
+// dropRedundantParens removes one layer of parentheses that can't change meaning:
+// around the whole expression, directly inside another pair, or around a single token.
+// A pair that is the only argument of a call stays: f((a, b)) passes one row, f(a, b) two values.
+func dropRedundantParens(toks []token) []token {
+	match := matchParens(toks)
+	for _, open := range slices.Sorted(maps.Keys(match)) {
+		end := match[open]
+		switch {
+		case open == 0 && end == len(toks)-1:
+		case open > 0 && toks[open-1].text == "(" && match[open-1] == end+1 && !isCallee(toks, open-1):
+		case end-open == 2 && !isCallee(toks, open):
+		default:
+			continue
+		}
+		// one pair per pass keeps the indexes valid
+		out := slices.Delete(slices.Clone(toks), end, end+1)
+		return slices.Delete(out, open, open+1)
+	}
+	return toks
+}
+
+// matchParens maps each "(" index to its ")" index. Unbalanced input yields no pairs.
+func matchParens(toks []token) map[int]int {
+	match := map[int]int{}
+	var stack []int
+	for i, t := range toks {
+		switch t.text {
+		case "(":
+			stack = append(stack, i)
+		case ")":
+			if len(stack) == 0 {
+				return nil
+			}
+			match[stack[len(stack)-1]] = i
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) > 0 {
+		return nil
+	}
+	return match
+}
+
+// keywords that may precede a parenthesized operand without making it a call.
+// ANY and ALL take one array operand, so extra parentheses inside theirs are redundant too.
+var operandKeywords = []string{"and", "or", "not", "is", "when", "then", "else", "between", "like", "ilike", "using", "where", "any", "all"}
+
+// isCallee reports whether the "(" at i opens a function call's or type's argument list.
+func isCallee(toks []token, i int) bool {
+	if i == 0 {
+		return false
+	}
+	prev := toks[i-1]
+	return (prev.kind == word || prev.kind == quoted) && !slices.Contains(operandKeywords, prev.text)
+}
+
+// ---------- output ----------
+
+// render joins tokens with canonical spacing: "f(a, b) = 'x'".
+func render(toks []token) string {
+	var b strings.Builder
+	for i, t := range toks {
+		if i > 0 && spaceBetween(toks[i-1], t) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+func spaceBetween(prev, t token) bool {
+	switch {
+	case prev.text == "(" || prev.text == "[":
+		return false
+	case t.text == ")" || t.text == "]" || t.text == ",":
+		return false
+	case prev.text == "::" || t.text == "::":
+		return false
+	case t.text == "(" || t.text == "[":
+		// calls and array subscripts hug their callee
+		return !(prev.kind == word || prev.kind == quoted || prev.text == "]" || prev.text == ")") ||
+			slices.Contains(operandKeywords, prev.text) || prev.text == "any" || prev.text == "all" || prev.text == "in"
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package normalize
+
+import "testing"
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name, clause, want string
+	}{
+		{"text casts", "((status)::text = 'a'::text)", "status = 'a'"},
+		{"varchar casts", "((status)::character varying = 'a'::character varying)", "status = 'a'"},
+		{"bpchar literal", "(code = 'ab'::bpchar)", "code = 'ab'"},
+		{"length modifier kept", "((code)::character(2) = 'ab'::bpchar)", "code::character(2) = 'ab'"},
+		{"varchar modifier kept", "((code)::character varying(3) = 'abc'::text)", "code::character varying(3) = 'abc'"},
+		{"bare character kept", "((code)::character = 'a'::text)", "code::character = 'a'"},
+		{"other casts kept", "((age)::bigint > 0)", "age::bigint > 0"},
+		{"qualifiers", "(pg_catalog.lower(public.t.name) <> ''::text)", "lower(t.name) <> ''"},
+		{"nested parens", "(((a > 0)) AND ((b > 0)))", "(a > 0) and (b > 0)"},
+		{"row argument kept", "(f((a, b)) > 0)", "f((a, b)) > 0"},
+		{"row constructor kept", "(row((a, b)) IS NOT NULL)", "row((a, b)) is not null"},
+		{"parenthesized argument kept", "(f((a + b)) > 0)", "f((a + b)) > 0"},
+		{"any operand", "(x = ANY ((ARRAY[1, 2])))", "x = any (array[1, 2])"},
+		{"in list kept", "(x IN ((1, 2)))", "x in ((1, 2))"},
+		{"single-token argument", "(f((a)) > 0)", "f(a) > 0"},
+		{"call keeps its parens", "(length(name) > 0)", "length(name) > 0"},
+		{"literals keep case", "(status = 'Active'::text)", "status = 'Active'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Check(tt.clause); got != tt.want {
+				t.Errorf("Check(%q) = %q, want %q", tt.clause, got, tt.want)
+			}
+		})
+	}
+}
+
+// Clauses and definitions as PostgreSQL 14 and 16 print the same constraint or index.
+func TestPG14VersusPG16(t *testing.T) {
+	checks := []struct{ pg14, pg16 string }{
+		{
+			"((status)::text = ANY ((ARRAY['a'::character varying, 'b'::character varying])::text[]))",
+			"((status)::text = ANY (ARRAY['a'::text, 'b'::text]))",
+		},
+		{"((email)::text ~~ '%@%'::text)", "((email)::text ~~ '%@%'::character varying)"},
+	}
+	for _, c := range checks {
+		if a, b := Check(c.pg14), Check(c.pg16); a != b {
+			t.Errorf("Check differs:\n  PG14 %q → %q\n  PG16 %q → %q", c.pg14, a, c.pg16, b)
+		}
+	}
+
+	indexes := []struct{ pg14, pg16 string }{
+		{
+			"CREATE UNIQUE INDEX users_email_key ON public.users USING btree (lower((email)::text))",
+			"CREATE UNIQUE INDEX users_email_key ON users USING btree (lower((email)::character varying))",
+		},
+	}
+	for _, c := range indexes {
+		if a, b := IndexDef(c.pg14), IndexDef(c.pg16); a != b {
+			t.Errorf("IndexDef differs:\n  PG14 %q → %q\n  PG16 %q → %q", c.pg14, a, c.pg16, b)
+		}
+	}
+}
+
+func TestIndexDef(t *testing.T) {
+	tests := []struct{ def, want string }{
+		{"CREATE INDEX i ON public.t USING btree (lower((email)::text))", "create index i on t using btree(lower(email))"},
+		{"CREATE INDEX i ON t USING btree (a, b) WHERE (deleted_at IS NULL)", "create index i on t using btree(a, b) where (deleted_at is null)"},
+	}
+	for _, tt := range tests {
+		if got := IndexDef(tt.def); got != tt.want {
+			t.Errorf("IndexDef(%q) = %q, want %q", tt.def, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Package policy holds the rules that limit which migration steps may run against a database.
+package policy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Saba101/GoMetaSync/internal/filter"
+)
+
+// Policy limits what --mode apply may run against a database. Allow and Deny are patterns
+// (glob, or re:<regexp>) matched against step kinds such as "column_dropped":
+//
+//	apply:
+//	  deny: ["*_dropped", "column_changed"]   # never drop or retype in prod
+//	  lockTimeout: 3s
+type Policy struct {
+	Allow []string `yaml:"allow"` // if set, only matching steps may run
+	Deny  []string `yaml:"deny"`  // matching steps never run
+
+	LockTimeout      time.Duration `yaml:"lockTimeout"`      // 0 means the applier's default
+	StatementTimeout time.Duration `yaml:"statementTimeout"` // 0 means no limit
+}
+
+// Merge returns p with the patterns of o added and o's timeouts where set.
+func (p Policy) Merge(o Policy) Policy {
+	p.Allow = append(append([]string(nil), p.Allow...), o.Allow...)
+	p.Deny = append(append([]string(nil), p.Deny...), o.Deny...)
+	if o.LockTimeout != 0 {
+		p.LockTimeout = o.LockTimeout
+	}
+	if o.StatementTimeout != 0 {
+		p.StatementTimeout = o.StatementTimeout
+	}
+	return p
+}
+
+// Validate checks the allow and deny patterns.
+func (p Policy) Validate() error {
+	for _, pat := range append(append([]string(nil), p.Allow...), p.Deny...) {
+		if err := filter.CheckPattern(pat); err != nil {
+			return fmt.Errorf("policy: %w", err)
+		}
+	}
+	return nil
+}
+
+// Blocked returns why the policy refuses steps of kind, or "" if they may run.
+func (p Policy) Blocked(kind string) string {
+	for _, pat := range p.Deny {
+		if filter.Match(pat, kind) {
+			return "denied by " + pat
+		}
+	}
+	if len(p.Allow) == 0 {
+		return ""
+	}
+	for _, pat := range p.Allow {
+		if filter.Match(pat, kind) {
+			return ""
+		}
+	}
+	return "not in the allow list"
+}
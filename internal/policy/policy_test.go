@@ -0,0 +1,49 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBlocked(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		kind   string
+		want   string
+	}{
+		{"no policy", Policy{}, "column_dropped", ""},
+		{"denied", Policy{Deny: []string{"*_dropped"}}, "column_dropped", "denied by *_dropped"},
+		{"not denied", Policy{Deny: []string{"*_dropped"}}, "column_added", ""},
+		{"allowed", Policy{Allow: []string{"column_*"}}, "column_added", ""},
+		{"not allowed", Policy{Allow: []string{"column_*"}}, "index_added", "not in the allow list"},
+		{"deny wins over allow", Policy{Allow: []string{"column_*"}, Deny: []string{"re:.*_dropped"}}, "column_dropped", "denied by re:.*_dropped"},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Blocked(tt.kind); got != tt.want {
+			t.Errorf("%s: Blocked = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := Policy{Deny: []string{"*_dropped"}, LockTimeout: time.Second, StatementTimeout: time.Minute}
+	got := base.Merge(Policy{Deny: []string{"column_changed"}, LockTimeout: 3 * time.Second})
+
+	if strings.Join(got.Deny, ",") != "*_dropped,column_changed" || got.LockTimeout != 3*time.Second || got.StatementTimeout != time.Minute {
+		t.Errorf("Merge = %+v", got)
+	}
+	if len(base.Deny) != 1 {
+		t.Errorf("Merge changed its receiver: %+v", base)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := (Policy{Allow: []string{"column_*"}, Deny: []string{"re:index_(added"}}).Validate(); err == nil || !strings.Contains(err.Error(), "policy: ") {
+		t.Errorf("err = %v, want a policy error for the bad regexp", err)
+	}
+	if err := (Policy{Allow: []string{"column_*"}}).Validate(); err != nil {
+		t.Errorf("err = %v", err)
+	}
+}
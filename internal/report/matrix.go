@@ -0,0 +1,318 @@
+// Package report renders multi-environment drift reports.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/snapshot"
+)
+
+// Env is one environment's snapshot in a matrix.
+type Env struct {
+	Name string
+	Snap *models.Snapshot
+}
+
+// Status is how an environment's version of an object compares with the baseline's.
+type Status string
+
+const (
+	StatusSame    Status = "same"    // identical to the baseline
+	StatusDiffers Status = "differs" // present in both, different definition
+	StatusMissing Status = "missing" // in the baseline, not here
+	StatusExtra   Status = "extra"   // here, not in the baseline
+	StatusAbsent  Status = "absent"  // in neither this environment nor the baseline
+)
+
+// Matrix lists every object that isn't identical in all environments.
+type Matrix struct {
+	Baseline string   `json:"baseline"`
+	Envs     []string `json:"envs"`
+	Rows     []Row    `json:"rows"`
+}
+
+// Row is one object across environments.
+type Row struct {
+	Object snapshot.Object `json:"object"`
+	Path   string          `json:"path"`
+	Cells  []Cell          `json:"cells"` // one per environment, in Matrix.Envs order
+	// Versions are the distinct definitions, labeled A, B, … with the baseline's first
+	Versions []Version `json:"versions,omitempty"`
+}
+
+// Cell is one environment's version of an object.
+type Cell struct {
+	Env     string `json:"env"`
+	Status  Status `json:"status"`
+	Version string `json:"version,omitempty"` // label in Row.Versions, empty if the object is absent
+}
+
+// Version is one distinct definition of an object.
+type Version struct {
+	Label      string `json:"label"`
+	Definition string `json:"definition"`
+}
+
+// BuildMatrix compares the environments object by object against baseline, which must be one of them.
+func BuildMatrix(envs []Env, baseline string) (*Matrix, error) {
+	m := &Matrix{Baseline: baseline}
+	base := -1
+	for i, e := range envs {
+		if slices.Contains(m.Envs, e.Name) {
+			return nil, fmt.Errorf("report: environment %q given twice", e.Name)
+		}
+		m.Envs = append(m.Envs, e.Name)
+		if e.Name == baseline {
+			base = i
+		}
+	}
+	if base < 0 {
+		return nil, fmt.Errorf("report: baseline %q is not one of %v", baseline, m.Envs)
+	}
+
+	type key struct {
+		obj  snapshot.Object
+		path string
+	}
+	defs := map[key][]*string{} // per environment, nil when absent
+	var keys []key
+	for i, e := range envs {
+		for _, d := range snapshot.Definitions(e.Snap) {
+			k := key{d.Object, d.Path()}
+			if _, ok := defs[k]; !ok {
+				defs[k] = make([]*string, len(envs))
+				keys = append(keys, k)
+			}
+			def := d.Def
+			defs[k][i] = &def
+		}
+	}
+	slices.SortFunc(keys, func(a, b key) int {
+		if c := strings.Compare(a.path, b.path); c != 0 {
+			return c
+		}
+		// tables before their primary keys, which share their path
+		return objectRank(a.obj) - objectRank(b.obj)
+	})
+
+	// statuses of tables and schemas that differ, so their contents aren't listed again
+	parents := map[string][]Status{}
+
+	for _, k := range keys {
+		row := Row{Object: k.obj, Path: k.path}
+		labels := map[string]string{}
+		label := func(def string) string {
+			if l, ok := labels[def]; ok {
+				return l
+			}
+			l := string(rune('A' + len(labels)))
+			labels[def] = l
+			row.Versions = append(row.Versions, Version{Label: l, Definition: def})
+			return l
+		}
+
+		vals := defs[k]
+		if vals[base] != nil {
+			label(*vals[base])
+		}
+		identical := true
+		for i, v := range vals {
+			cell := Cell{Env: envs[i].Name}
+			switch {
+			case v == nil && vals[base] == nil:
+				cell.Status = StatusAbsent
+			case v == nil:
+				cell.Status = StatusMissing
+			case vals[base] == nil:
+				cell.Status, cell.Version = StatusExtra, label(*v)
+			case *v == *vals[base]:
+				cell.Status, cell.Version = StatusSame, label(*v)
+			default:
+				cell.Status, cell.Version = StatusDiffers, label(*v)
+			}
+			if cell.Status != StatusSame {
+				identical = false
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		if identical {
+			continue
+		}
+		if k.obj == snapshot.ObjectSchema || k.obj == snapshot.ObjectTable {
+			parents[k.path] = statuses(row.Cells)
+		}
+		if explained(row.Cells, parents[parentPath(k.obj, k.path)]) {
+			continue
+		}
+		// presence-only objects (schemas, tables) have nothing to tell apart
+		if len(row.Versions) == 1 && row.Versions[0].Definition == "" {
+			row.Versions = nil
+			for i := range row.Cells {
+				row.Cells[i].Version = ""
+			}
+		}
+		m.Rows = append(m.Rows, row)
+	}
+	return m, nil
+}
+
+func objectRank(obj snapshot.Object) int {
+	return slices.Index(snapshot.Objects, obj)
+}
+
+func statuses(cells []Cell) []Status {
+	var out []Status
+	for _, c := range cells {
+		out = append(out, c.Status)
+	}
+	return out
+}
+
+// parentPath is the path of the schema of a table, or the table of any other object.
+func parentPath(obj snapshot.Object, path string) string {
+	switch obj {
+	case snapshot.ObjectSchema:
+		return ""
+	case snapshot.ObjectPrimaryKey:
+		// unnamed: its path is its table's
+		return path
+	}
+	return path[:strings.LastIndex(path, ".")]
+}
+
+// explained reports whether every difference of an object comes from its parent being
+// missing or extra in that environment.
+func explained(cells []Cell, parent []Status) bool {
+	if parent == nil {
+		return false
+	}
+	for i, c := range cells {
+		if c.Status != StatusSame && parent[i] != StatusMissing && parent[i] != StatusExtra && parent[i] != StatusAbsent {
+			return false
+		}
+	}
+	return true
+}
+
+// Symbol is the one-character marker of a status used by the Markdown and HTML renderers.
+func (s Status) Symbol() string {
+	switch s {
+	case StatusSame:
+		return "✅"
+	case StatusDiffers:
+		return "⚠️"
+	case StatusMissing:
+		return "❌"
+	case StatusExtra:
+		return "➕"
+	}
+	return "·"
+}
+
+func (c Cell) text() string {
+	if c.Version == "" {
+		return c.Status.Symbol()
+	}
+	return c.Status.Symbol() + " " + c.Version
+}
+
+// JSON writes the matrix as indented JSON.
+func (m *Matrix) JSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// Markdown writes the matrix as a Markdown table.
+func (m *Matrix) Markdown(w io.Writer) error {
+	fmt.Fprintf(w, "# Schema drift matrix\n\nBaseline: **%s**. ✅ same as baseline · ⚠️ different version · ❌ missing · ➕ not in baseline\n\n", m.Baseline)
+	if len(m.Rows) == 0 {
+		_, err := fmt.Fprintln(w, "All environments are identical.")
+		return err
+	}
+
+	head := []string{"Object", "Path"}
+	for _, e := range m.Envs {
+		if e == m.Baseline {
+			e += " (baseline)"
+		}
+		head = append(head, e)
+	}
+	head = append(head, "Versions")
+	fmt.Fprintf(w, "| %s |\n", strings.Join(head, " | "))
+	fmt.Fprintf(w, "|%s\n", strings.Repeat("---|", len(head)))
+
+	for _, r := range m.Rows {
+		cols := []string{string(r.Object), codeSpan(r.Path)}
+		for _, c := range r.Cells {
+			cols = append(cols, c.text())
+		}
+		var versions []string
+		for _, v := range r.Versions {
+			versions = append(versions, fmt.Sprintf("%s: %s", v.Label, codeSpan(v.Definition)))
+		}
+		cols = append(cols, strings.Join(versions, "<br>"))
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cols, " | "))
+	}
+	_, err := fmt.Fprintf(w, "\n%d objects differ across %d environments.\n", len(m.Rows), len(m.Envs))
+	return err
+}
+
+// codeSpan renders s as a Markdown code span that is safe in a table cell: the fence is one
+// backtick longer than any run in s, and pipes are escaped, which GFM tables require even in code.
+func codeSpan(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", longest+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return fence + strings.ReplaceAll(s, "|", `\|`) + fence
+}
+
+// HTML writes the matrix as a standalone HTML page.
+func (m *Matrix) HTML(w io.Writer) error {
+	return htmlTmpl.Execute(w, m)
+}
+
+var htmlTmpl = template.Must(template.New("matrix").Funcs(template.FuncMap{
+	"text": Cell.text,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Schema drift matrix</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th.baseline { background: #eef; }
+td.same { background: #efe; } td.differs { background: #ffd; } td.missing { background: #fdd; } td.extra { background: #ddf; }
+code { font-size: 90%; }
+</style>
+</head>
+<body>
+<h1>Schema drift matrix</h1>
+<p>Baseline: <b>{{.Baseline}}</b>. ✅ same as baseline · ⚠️ different version · ❌ missing · ➕ not in baseline</p>
+{{if not .Rows}}<p>All environments are identical.</p>{{else}}
+<table>
+<tr><th>Object</th><th>Path</th>{{range .Envs}}<th{{if eq . $.Baseline}} class="baseline"{{end}}>{{.}}</th>{{end}}<th>Versions</th></tr>
+{{range .Rows}}<tr><td>{{.Object}}</td><td><code>{{.Path}}</code></td>{{range .Cells}}<td class="{{.Status}}">{{text .}}</td>{{end}}<td>{{range .Versions}}{{.Label}}: <code>{{.Definition}}</code><br>{{end}}</td></tr>
+{{end}}</table>
+<p>{{len .Rows}} objects differ across {{len .Envs}} environments.</p>{{end}}
+</body>
+</html>
+`))
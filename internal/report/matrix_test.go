@@ -0,0 +1,134 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/models/modelstest"
+)
+
+func table(name string, cols map[string]string) models.TableSnapshot {
+	return models.TableSnapshot{Name: name, Columns: cols}
+}
+
+func TestBuildMatrix(t *testing.T) {
+	users := table("users", map[string]string{"id": "integer", "email": "text"})
+	usersBigint := table("users", map[string]string{"id": "bigint", "email": "text"})
+	audit := table("audit", map[string]string{"id": "integer"})
+
+	envs := []Env{
+		{Name: "dev", Snap: modelstest.Snapshot(usersBigint, audit)},
+		{Name: "prod", Snap: modelstest.Snapshot(users)},
+		{Name: "staging", Snap: modelstest.Snapshot(table("users", map[string]string{"id": "integer"}))},
+	}
+	m, err := BuildMatrix(envs, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, r := range m.Rows {
+		line := string(r.Object) + " " + r.Path + ":"
+		for _, c := range r.Cells {
+			line += " " + string(c.Status) + c.Version
+		}
+		got = append(got, line)
+	}
+	// audit's columns aren't listed again: the table being extra explains them
+	want := []string{
+		"table app.public.audit: extra absent absent",
+		"column app.public.users.email: sameA sameA missing",
+		"column app.public.users.id: differsB sameA sameA",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("rows:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBuildMatrixErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		envs     []Env
+		baseline string
+		wantErr  string
+	}{
+		{"unknown baseline", []Env{{Name: "dev", Snap: modelstest.Snapshot()}}, "prod", `baseline "prod" is not one of [dev]`},
+		{"duplicate env", []Env{{Name: "dev", Snap: modelstest.Snapshot()}, {Name: "dev", Snap: modelstest.Snapshot()}}, "dev", `environment "dev" given twice`},
+	}
+	for _, tt := range tests {
+		if _, err := BuildMatrix(tt.envs, tt.baseline); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	envs := []Env{{Name: "dev", Snap: modelstest.Snapshot(table("users", map[string]string{"id": "bigint"}))}, {Name: "prod", Snap: modelstest.Snapshot(table("users", map[string]string{"id": "integer"}))}}
+	m, err := BuildMatrix(envs, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := m.Markdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"| Object | Path | dev | prod (baseline) | Versions |", "| column | `app.public.users.id` | ⚠️ B | ✅ A |", "1 objects differ across 2 environments."} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("markdown lacks %q:\n%s", s, b.String())
+		}
+	}
+
+	same, _ := BuildMatrix(envs[1:], "prod")
+	b.Reset()
+	same.Markdown(&b)
+	if !strings.Contains(b.String(), "All environments are identical.") {
+		t.Errorf("markdown of identical environments:\n%s", b.String())
+	}
+}
+
+// NOT NULL pseudo check constraints are named after OIDs, so the same column differs in name only.
+func TestBuildMatrixNotNull(t *testing.T) {
+	withCheck := func(name string) *models.Snapshot {
+		tbl := table("users", map[string]string{"id": "integer"})
+		tbl.CheckConstraints = map[string]string{name: "id IS NOT NULL"}
+		return modelstest.Snapshot(tbl)
+	}
+	m, err := BuildMatrix([]Env{{Name: "dev", Snap: withCheck("2200_16386_1_not_null")}, {Name: "prod", Snap: withCheck("2200_24571_1_not_null")}}, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Rows) > 0 {
+		t.Errorf("rows = %+v, want none", m.Rows)
+	}
+}
+
+func TestMarkdownEscapes(t *testing.T) {
+	withCheck := func(clause string) *models.Snapshot {
+		tbl := table("users", map[string]string{"a": "text", "b": "text"})
+		tbl.CheckConstraints = map[string]string{"users_ab_check": clause}
+		return modelstest.Snapshot(tbl)
+	}
+	m, err := BuildMatrix([]Env{{Name: "dev", Snap: withCheck("(a || b) <> '`'")}, {Name: "prod", Snap: withCheck("a <> b")}}, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := m.Markdown(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	var row string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if strings.HasPrefix(line, "| check ") {
+			row = line
+		}
+	}
+	if !strings.Contains(row, "B: ``(a \\|\\| b) <> '`'``") {
+		t.Errorf("row doesn't escape the clause: %s", row)
+	}
+	// Object, Path, dev, prod, Versions: six unescaped pipes
+	if n := strings.Count(row, "|") - strings.Count(row, `\|`); n != 6 {
+		t.Errorf("row has %d cell separators, want 6: %s", n, row)
+	}
+}
@@ -0,0 +1,382 @@
+package snapshot
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/typecompat"
+)
+
+// Action is what happened to an object between two snapshots.
+type Action string
+
+const (
+	Added   Action = "added"
+	Dropped Action = "dropped"
+	Changed Action = "changed"
+	Renamed Action = "renamed"
+)
+
+// Object is the type of schema object a Change is about.
+type Object string
+
+const (
+	ObjectDatabase   Object = "database"
+	ObjectSchema     Object = "schema"
+	ObjectTable      Object = "table"
+	ObjectColumn     Object = "column"
+	ObjectPrimaryKey Object = "primary_key"
+	ObjectUnique     Object = "unique"
+	ObjectCheck      Object = "check"
+	ObjectForeignKey Object = "foreign_key"
+	ObjectIndex      Object = "index"
+)
+
+// Objects lists the object types in output order: containers before their contents,
+// columns before the constraints and indexes built on them.
+var Objects = []Object{
+	ObjectDatabase, ObjectSchema, ObjectTable, ObjectColumn, ObjectPrimaryKey,
+	ObjectUnique, ObjectCheck, ObjectForeignKey, ObjectIndex,
+}
+
+// actions in output order; a confirmed rename that also changed shape lists the rename first.
+var actions = []Action{Dropped, Renamed, Changed, Added}
+
+// Change is a single difference between two snapshots.
+//
+// Old and New hold the object on each side, typed by Object:
+// database → models.DatabaseSnapshot, schema → models.SchemaSnapshot, table → models.TableSnapshot, column → data type string,
+// primary_key and unique → []string columns, check → clause string,
+// foreign_key → models.ForeignKey, index → models.Index.
+type Change struct {
+	Action Action `json:"action"`
+	Object Object `json:"object"`
+	DB     string `json:"db"`
+	Schema string `json:"schema"`
+	Table  string `json:"table,omitempty"`
+	Name   string `json:"name,omitempty"` // column, constraint or index name
+	Old    any    `json:"old,omitempty"`
+	New    any    `json:"new,omitempty"`
+
+	// Set on renames only
+	OldName      string  `json:"old_name,omitempty"`      // previous Table (for tables) or Name
+	Confidence   float64 `json:"confidence,omitempty"`    // 0..1, heuristic unless Confirmed
+	Confirmed    bool    `json:"confirmed,omitempty"`     // confirmed by a rename hint
+	ByDefinition bool    `json:"by_definition,omitempty"` // matched by identical definition (DiffOptions.MatchByDefinition)
+
+	// Set on column type changes only
+	TypeChange *typecompat.Result `json:"type_change,omitempty"`
+
+	// Ordinal position of an added or dropped column, when the snapshot records it
+	Position int `json:"position,omitempty"`
+}
+
+// Severity ranks how much a change matters to the applications using the database.
+type Severity string
+
+const (
+	SeverityLow    Severity = "low"
+	SeverityMedium Severity = "medium"
+	SeverityHigh   Severity = "high"
+)
+
+// Severity of the change: drops are high, changes medium, additions low.
+// Renaming a table or column breaks queries (medium); renaming a constraint or index doesn't (low).
+// Column type changes follow their class: widening is low, incompatible high.
+func (c Change) Severity() Severity {
+	switch c.Action {
+	case Dropped:
+		return SeverityHigh
+	case Changed:
+		if c.TypeChange != nil {
+			switch c.TypeChange.Class {
+			case typecompat.Widening:
+				return SeverityLow
+			case typecompat.Incompatible:
+				return SeverityHigh
+			}
+		}
+		return SeverityMedium
+	case Renamed:
+		if c.Object == ObjectTable || c.Object == ObjectColumn {
+			return SeverityMedium
+		}
+	}
+	return SeverityLow
+}
+
+// Kind is the change type used by suppression rules, e.g. "column_dropped".
+func (c Change) Kind() string {
+	return string(c.Object) + "_" + string(c.Action)
+}
+
+// Path is the dotted object path, e.g. "app.public.users.email".
+func (c Change) Path() string {
+	parts := []string{c.DB}
+	if c.Schema != "" {
+		parts = append(parts, c.Schema)
+	}
+	if c.Table != "" {
+		parts = append(parts, c.Table)
+	}
+	if c.Name != "" {
+		parts = append(parts, c.Name)
+	}
+	return strings.Join(parts, ".")
+}
+
+// String renders the change as one line of human-readable diff output.
+func (c Change) String() string {
+	tbl := fmt.Sprintf("%s.%s.%s", c.DB, c.Schema, c.Table)
+
+	if c.Action == Renamed {
+		return c.renameString()
+	}
+
+	switch c.Object {
+	case ObjectDatabase:
+		if c.Action == Dropped {
+			return fmt.Sprintf("❌ Database dropped: %s", c.DB)
+		}
+
+	case ObjectSchema:
+		switch c.Action {
+		case Added:
+			return fmt.Sprintf("✅ New schema: %s.%s", c.DB, c.Schema)
+		case Dropped:
+			return fmt.Sprintf("❌ Schema dropped: %s.%s", c.DB, c.Schema)
+		}
+
+	case ObjectTable:
+		switch c.Action {
+		case Added:
+			return fmt.Sprintf("✅ New table: %s", tbl)
+		case Dropped:
+			return fmt.Sprintf("❌ Table dropped: %s", tbl)
+		}
+
+	case ObjectColumn:
+		switch c.Action {
+		case Added:
+			return fmt.Sprintf("✅ New column: %s.%s (%s)", tbl, c.Name, c.New)
+		case Dropped:
+			return fmt.Sprintf("❌ Column dropped: %s.%s", tbl, c.Name)
+		case Changed:
+			if c.TypeChange != nil {
+				return fmt.Sprintf("⚠️ Type changed: %s.%s (%s → %s) [%s]", tbl, c.Name, c.Old, c.New, c.TypeChange)
+			}
+			return fmt.Sprintf("⚠️ Type changed: %s.%s (%s → %s)", tbl, c.Name, c.Old, c.New)
+		}
+
+	case ObjectPrimaryKey:
+		switch c.Action {
+		case Added:
+			return fmt.Sprintf("✅ Primary key set: %s (%v)", tbl, c.New)
+		case Dropped:
+			return fmt.Sprintf("❌ Primary key dropped: %s (was %v)", tbl, c.Old)
+		case Changed:
+			return fmt.Sprintf("🔁 Primary key changed: %s (%v → %v)", tbl, c.Old, c.New)
+		}
+
+	case ObjectUnique:
+		switch c.Action {
+		case Added:
+			return fmt.Sprintf("✅ Unique constraint added: %s %s (%v)", tbl, c.Name, c.New)
+		case Dropped:
+			return fmt.Sprintf("❌ Unique constraint dropped: %s %s", tbl, c.Name)
+		case Changed:
+			return fmt.Sprintf("🔁 Unique constraint changed: %s %s (%v → %v)", tbl, c.Name, c.Old, c.New)
+		}
+
+	case ObjectCheck:
+		switch c.Action {
+		case Added:
+			return fmt.Sprintf("✅ Check constraint added: %s %s", tbl, c.Name)
+		case Dropped:
+			return fmt.Sprintf("❌ Check constraint dropped: %s %s", tbl, c.Name)
+		case Changed:
+			return fmt.Sprintf("🔁 Check constraint changed: %s %s", tbl, c.Name)
+		}
+
+	case ObjectForeignKey:
+		switch c.Action {
+		case Added:
+			fk, _ := c.New.(models.ForeignKey)
+			return fmt.Sprintf("✅ Foreign key added: %s %s (%v → %s.%s %v)",
+				tbl, c.Name, fk.Columns, fk.RefSchema, fk.RefTable, fk.RefColumns)
+		case Dropped:
+			return fmt.Sprintf("❌ Foreign key dropped: %s %s", tbl, c.Name)
+		case Changed:
+			return fmt.Sprintf("🔁 Foreign key changed: %s %s", tbl, c.Name)
+		}
+
+	case ObjectIndex:
+		switch c.Action {
+		case Added:
+			idx, _ := c.New.(models.Index)
+			return fmt.Sprintf("✅ Index added: %s %s (unique=%v cols=%v)", tbl, c.Name, idx.Unique, idx.Columns)
+		case Dropped:
+			return fmt.Sprintf("❌ Index dropped: %s %s", tbl, c.Name)
+		case Changed:
+			return fmt.Sprintf("🔁 Index changed: %s %s", tbl, c.Name)
+		}
+	}
+
+	return fmt.Sprintf("%s %s: %s", c.Object, c.Action, c.Path())
+}
+
+func (c Change) renameString() string {
+	how := fmt.Sprintf("confidence %.2f", c.Confidence)
+	switch {
+	case c.Confirmed:
+		how = "confirmed"
+	case c.ByDefinition:
+		how = "same definition, severity " + string(c.Severity())
+	}
+	if c.Object == ObjectTable {
+		return fmt.Sprintf("✏️ Table renamed: %s.%s.%s → %s (%s)", c.DB, c.Schema, c.OldName, c.Table, how)
+	}
+	if c.Object == ObjectColumn {
+		return fmt.Sprintf("✏️ Column renamed: %s.%s.%s.%s → %s (%s)", c.DB, c.Schema, c.Table, c.OldName, c.Name, how)
+	}
+	label := map[Object]string{
+		ObjectUnique:     "Unique constraint",
+		ObjectCheck:      "Check constraint",
+		ObjectForeignKey: "Foreign key",
+		ObjectIndex:      "Index",
+	}[c.Object]
+	return fmt.Sprintf("✏️ %s renamed: %s.%s.%s %s → %s (%s)", label, c.DB, c.Schema, c.Table, c.OldName, c.Name, how)
+}
+
+// Sort orders changes by database, schema, table, object type (in Objects order), name and action,
+// so diff output is the same on every run.
+func Sort(changes []Change) {
+	slices.SortStableFunc(changes, func(a, b Change) int {
+		return cmp.Or(
+			strings.Compare(a.DB, b.DB),
+			strings.Compare(a.Schema, b.Schema),
+			strings.Compare(a.Table, b.Table),
+			slices.Index(Objects, a.Object)-slices.Index(Objects, b.Object),
+			strings.Compare(a.Name, b.Name),
+			slices.Index(actions, a.Action)-slices.Index(actions, b.Action),
+		)
+	})
+}
+
+// Group is the heading a change is listed under: its table, its schema for schema changes,
+// or its database for database changes.
+func (c Change) Group() string {
+	if c.Schema == "" {
+		return c.DB
+	}
+	if c.Table == "" {
+		return c.DB + "." + c.Schema
+	}
+	return tablePath(c)
+}
+
+// Invert returns the changes that undo changes, e.g. for a down migration.
+//
+// It follows the conventions of Compare: a dropped table or schema doesn't list its contents,
+// and an added one lists everything in it, so re-adding what was dropped spells out the
+// columns, constraints and indexes of the old objects.
+func Invert(changes []Change) []Change {
+	addedTables := map[string]bool{}
+	addedSchemas := map[string]bool{}
+	renamedTables := map[string]string{} // new table path -> old name
+	for _, c := range changes {
+		switch {
+		case c.Object == ObjectTable && c.Action == Added:
+			addedTables[tablePath(c)] = true
+		case c.Object == ObjectSchema && c.Action == Added:
+			addedSchemas[c.DB+"."+c.Schema] = true
+		case c.Object == ObjectTable && c.Action == Renamed:
+			renamedTables[tablePath(c)] = c.OldName
+		}
+	}
+
+	var out []Change
+	for _, c := range changes {
+		// the inverse drops added schemas and tables whole, without listing their contents
+		inAddedSchema := c.Object != ObjectSchema && addedSchemas[c.DB+"."+c.Schema]
+		inAddedTable := c.Object != ObjectSchema && c.Object != ObjectTable && addedTables[tablePath(c)]
+		if inAddedSchema || inAddedTable {
+			continue
+		}
+
+		// a dropped database comes back as its schemas, as Compare lists a database that is only on the new side
+		if c.Object == ObjectDatabase {
+			if db, ok := c.Old.(models.DatabaseSnapshot); ok && c.Action == Dropped {
+				for _, name := range slices.Sorted(maps.Keys(db.Schemas)) {
+					s := Change{Action: Added, Object: ObjectSchema, DB: c.DB, Schema: name, New: db.Schemas[name]}
+					out = append(out, s)
+					out = append(out, schemaContents(s)...)
+				}
+			}
+			continue
+		}
+
+		inv := c
+		inv.Old, inv.New = c.New, c.Old
+		switch c.Action {
+		case Added:
+			inv.Action = Dropped
+		case Dropped:
+			inv.Action = Added
+		case Renamed:
+			if c.Object == ObjectTable {
+				inv.Table, inv.OldName = c.OldName, c.Table
+			} else {
+				inv.Name, inv.OldName = c.OldName, c.Name
+			}
+		}
+		if c.Object == ObjectColumn && c.Action == Changed {
+			oldType, _ := inv.Old.(string)
+			newType, _ := inv.New.(string)
+			inv.TypeChange = columnChange(c.Name, oldType, newType).TypeChange
+		}
+		// the inverse renames tables back before touching their contents
+		if old, ok := renamedTables[tablePath(c)]; ok && c.Object != ObjectTable {
+			inv.Table = old
+		}
+		out = append(out, inv)
+
+		if inv.Action != Added {
+			continue
+		}
+		switch c.Object {
+		case ObjectSchema:
+			out = append(out, schemaContents(inv)...)
+		case ObjectTable:
+			out = append(out, tableContents(inv)...)
+		}
+	}
+	return out
+}
+
+// schemaContents lists everything in an added schema as additions, as Compare does.
+func schemaContents(c Change) []Change {
+	s, _ := c.New.(models.SchemaSnapshot)
+	var out []Change
+	for _, tbl := range slices.Sorted(maps.Keys(s.Tables)) {
+		t := Change{Action: Added, Object: ObjectTable, DB: c.DB, Schema: c.Schema, Table: tbl, New: s.Tables[tbl]}
+		out = append(out, t)
+		out = append(out, tableContents(t)...)
+	}
+	return out
+}
+
+// tableContents lists everything in an added table as additions, as Compare does.
+func tableContents(c Change) []Change {
+	t, _ := c.New.(models.TableSnapshot)
+	var out []Change
+	for _, tc := range compareTables(models.TableSnapshot{}, t, false) {
+		tc.DB, tc.Schema, tc.Table = c.DB, c.Schema, c.Table
+		out = append(out, tc)
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package snapshot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/models/modelstest"
+)
+
+// Without renames, inverting a diff gives the diff the other way round.
+func TestInvertMatchesReverseCompare(t *testing.T) {
+	users := models.TableSnapshot{
+		Name:       "users",
+		Columns:    map[string]string{"id": "integer", "email": "text"},
+		PrimaryKey: []string{"id"},
+		Indexes: map[string]models.Index{"users_email_idx": {
+			Name: "users_email_idx", Columns: []string{"email"},
+			Definition: "CREATE INDEX users_email_idx ON public.users USING btree (email)",
+		}},
+	}
+	usersBigint := users
+	usersBigint.Columns = map[string]string{"id": "bigint", "email": "text", "nick": "text"}
+
+	noSchema := &models.Snapshot{Databases: map[string]models.DatabaseSnapshot{
+		"app": {DBName: "app", Schemas: map[string]models.SchemaSnapshot{}},
+	}}
+
+	tests := []struct {
+		name     string
+		old, new *models.Snapshot
+	}{
+		{"table added", modelstest.Snapshot(), modelstest.Snapshot(users)},
+		{"table dropped", modelstest.Snapshot(users), modelstest.Snapshot()},
+		{"schema added", noSchema, modelstest.Snapshot(users)},
+		{"columns added and changed", modelstest.Snapshot(users), modelstest.Snapshot(usersBigint)},
+		{"columns dropped and changed", modelstest.Snapshot(usersBigint), modelstest.Snapshot(users)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Invert(Compare(tt.old, tt.new, DiffOptions{}))
+			Sort(got)
+			want := Compare(tt.new, tt.old, DiffOptions{})
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Invert:\n%v\nwant:\n%v", got, want)
+			}
+		})
+	}
+}
+
+func TestInvertRenames(t *testing.T) {
+	changes := []Change{
+		{Action: Renamed, Object: ObjectTable, DB: "app", Schema: "public", Table: "customers", OldName: "users", Confirmed: true},
+		{Action: Renamed, Object: ObjectColumn, DB: "app", Schema: "public", Table: "customers", Name: "mail", OldName: "email", Confirmed: true},
+		{Action: Added, Object: ObjectColumn, DB: "app", Schema: "public", Table: "customers", Name: "nick", New: "text"},
+	}
+	want := []Change{
+		{Action: Renamed, Object: ObjectTable, DB: "app", Schema: "public", Table: "users", OldName: "customers", Confirmed: true},
+		{Action: Renamed, Object: ObjectColumn, DB: "app", Schema: "public", Table: "users", Name: "email", OldName: "mail", Confirmed: true},
+		{Action: Dropped, Object: ObjectColumn, DB: "app", Schema: "public", Table: "users", Name: "nick", Old: "text"},
+	}
+	if got := Invert(changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("Invert:\n%v\nwant:\n%v", got, want)
+	}
+}
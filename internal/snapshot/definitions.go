@@ -0,0 +1,92 @@
+package snapshot
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+)
+
+// Definition is one object of a snapshot. Two objects with the same Object and Path are the same
+// version when their Def is equal, compared the way Compare does (normalized, ignoring names).
+type Definition struct {
+	Object Object `json:"object"`
+	DB     string `json:"db"`
+	Schema string `json:"schema"`
+	Table  string `json:"table,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Def    string `json:"definition,omitempty"` // empty for schemas and tables, whose contents are listed separately
+}
+
+// Path is the dotted object path, as Change.Path.
+func (d Definition) Path() string {
+	return Change{DB: d.DB, Schema: d.Schema, Table: d.Table, Name: d.Name}.Path()
+}
+
+// Definitions lists every object of snap, sorted by path and object type.
+func Definitions(snap *models.Snapshot) []Definition {
+	var out []Definition
+	for db, dbSnap := range snap.Databases {
+		for schema, s := range dbSnap.Schemas {
+			out = append(out, Definition{Object: ObjectSchema, DB: db, Schema: schema})
+			for tbl, t := range s.Tables {
+				add := func(obj Object, name, def string) {
+					out = append(out, Definition{Object: obj, DB: db, Schema: schema, Table: tbl, Name: name, Def: def})
+				}
+				add(ObjectTable, "", "")
+				for col := range t.Columns {
+					add(ObjectColumn, col, t.ColumnType(col))
+				}
+				if len(t.PrimaryKey) > 0 {
+					add(ObjectPrimaryKey, "", strings.Join(t.PrimaryKey, ","))
+				}
+				for name, cols := range t.UniqueConstraints {
+					add(ObjectUnique, name, uniqueKey(cols))
+				}
+				for name, clause := range t.CheckConstraints {
+					// NOT NULL pseudo constraints are named after OIDs, which differ in every database
+					if col, ok := NotNullColumn(name, clause); ok {
+						name = col + "_not_null"
+					}
+					add(ObjectCheck, name, checkKey(clause))
+				}
+				for name, fk := range t.ForeignKeys {
+					add(ObjectForeignKey, name, foreignKeyKey(fk))
+				}
+				for name, idx := range t.Indexes {
+					add(ObjectIndex, name, indexKey(idx))
+				}
+			}
+		}
+	}
+	slices.SortFunc(out, func(a, b Definition) int {
+		if c := strings.Compare(a.Path(), b.Path()); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a.Object), string(b.Object))
+	})
+	return out
+}
+
+var (
+	notNullNameRe   = regexp.MustCompile(`^\d+_\d+_\d+_not_null$`)
+	notNullClauseRe = regexp.MustCompile(`^\(*\s*("(?:[^"]|"")+"|[^\s()]+)\s+IS\s+NOT\s+NULL\s*\)*$`)
+)
+
+// NotNullColumn recognizes the pseudo check constraints information_schema reports for NOT NULL
+// columns, named like 2200_16386_1_not_null, and returns the column.
+func NotNullColumn(name, clause string) (string, bool) {
+	if !notNullNameRe.MatchString(name) {
+		return "", false
+	}
+	m := notNullClauseRe.FindStringSubmatch(clause)
+	if m == nil {
+		return "", false
+	}
+	col := m[1]
+	if strings.HasPrefix(col, `"`) {
+		col = strings.ReplaceAll(col[1:len(col)-1], `""`, `"`)
+	}
+	return col, true
+}
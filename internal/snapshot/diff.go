@@ -1,26 +1,204 @@
 package snapshot
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"maps"
+	"os"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/normalize"
+	"github.com/Saba101/GoMetaSync/internal/typecompat"
 )
 
-func Diff(oldSnap, newSnap *models.Snapshot) {
+// DiffOptions controls Diff.
+type DiffOptions struct {
+	// Suppressions hide expected drift; suppressed changes are still listed in their own section.
+	Suppressions []Suppression
+	// Now is used to expire suppressions; zero means time.Now().
+	Now time.Time
+	// DetectRenames reports likely renames instead of a drop plus an add (see DetectRenames).
+	DetectRenames bool
+	// RenameHints confirm renames the heuristics miss or score low.
+	RenameHints []RenameHint
+	// MatchByDefinition matches constraints and indexes by definition before name,
+	// so differently auto-named copies of the same constraint are a low-severity rename.
+	MatchByDefinition bool
+	// ShowDefinitions prints the raw and normalized definitions under changed check constraints and indexes.
+	ShowDefinitions bool
+}
+
+// Diff compares two snapshots and prints the changes to stdout.
+func Diff(oldSnap, newSnap *models.Snapshot, opts DiffOptions) {
+	Fprint(os.Stdout, Compare(oldSnap, newSnap, opts), opts)
+}
+
+// Fprint writes a summary, then changes to w one line each under a heading per table,
+// followed by what the suppressions hid.
+func Fprint(w io.Writer, changes []Change, opts DiffOptions) {
+	kept, suppressed, expired := Suppress(changes, opts.Suppressions, opts.now())
+
+	fprintSummary(w, Summarize(kept))
+	for i, c := range kept {
+		// one heading per table (or schema), in the order Sort put them
+		if i == 0 || c.Group() != kept[i-1].Group() {
+			fmt.Fprintln(w)
+			fmt.Fprintf(w, "📂 %s\n", c.Group())
+		}
+		fmt.Fprintf(w, "  %s\n", c)
+		if opts.ShowDefinitions {
+			printDefinitions(w, c)
+		}
+	}
+	if len(suppressed) > 0 {
+		fmt.Fprintf(w, "\n🔕 Suppressed (%d):\n", len(suppressed))
+		for _, s := range suppressed {
+			fmt.Fprintf(w, "  %s\n      ↳ %s\n", s.Change, s.Rule)
+		}
+	}
+	for _, r := range expired {
+		fmt.Fprintf(w, "⏰ Suppression expired, no longer applied: %s\n", r)
+	}
+}
+
+func (o DiffOptions) now() time.Time {
+	if o.Now.IsZero() {
+		return time.Now()
+	}
+	return o.Now
+}
+
+func fprintSummary(w io.Writer, sum Summary) {
+	if sum.Changes == 0 {
+		fmt.Fprintln(w, "✅ No changes")
+		return
+	}
+	fmt.Fprintf(w, "📊 Summary: %s\n", sum.Stats)
+	fmt.Fprintf(w, "   %s\n", sum.ObjectCounts())
+	for _, db := range sum.Databases {
+		fmt.Fprintf(w, "   🗄️ %s: %s\n", db.Name, db.Stats)
+		for _, s := range db.Schemas {
+			fmt.Fprintf(w, "      %s.%s: %s\n", db.Name, s.Name, s.Stats)
+			fmt.Fprintf(w, "         %s\n", s.ObjectCounts())
+		}
+	}
+}
+
+// jsonDiff is the document written by FprintJSON.
+type jsonDiff struct {
+	Summary    Summary          `json:"summary"`
+	Changes    []Change         `json:"changes"`
+	Suppressed []jsonSuppressed `json:"suppressed,omitempty"`
+	Expired    []string         `json:"expired_suppressions,omitempty"`
+}
+
+type jsonSuppressed struct {
+	Change
+	Rule string `json:"rule"`
+}
+
+// FprintJSON writes the summary, changes and suppressed changes to w as one JSON document.
+func FprintJSON(w io.Writer, changes []Change, opts DiffOptions) error {
+	kept, suppressed, expired := Suppress(changes, opts.Suppressions, opts.now())
+	doc := jsonDiff{Summary: Summarize(kept), Changes: kept}
+	if doc.Changes == nil {
+		doc.Changes = []Change{}
+	}
+	for _, s := range suppressed {
+		doc.Suppressed = append(doc.Suppressed, jsonSuppressed{Change: s.Change, Rule: s.Rule.String()})
+	}
+	for _, r := range expired {
+		doc.Expired = append(doc.Expired, r.String())
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// FprintMarkdown writes the summary as tables and the changes as lists under a heading per table,
+// e.g. for a pull request comment.
+func FprintMarkdown(w io.Writer, changes []Change, opts DiffOptions) {
+	kept, suppressed, expired := Suppress(changes, opts.Suppressions, opts.now())
+	sum := Summarize(kept)
+
+	fmt.Fprintf(w, "## Schema diff\n\n**%s**\n", sum.Stats)
+	if sum.Changes > 0 {
+		fmt.Fprintf(w, "\n| Scope | Changes | Breaking | Non-breaking | Tables touched | By object |\n|---|---|---|---|---|---|\n")
+		row := func(scope string, st Stats) {
+			fmt.Fprintf(w, "| %s | %d | %d | %d | %d | %s |\n", scope, st.Changes, st.Breaking, st.NonBreaking, st.TablesTouched, st.ObjectCounts())
+		}
+		row("**total**", sum.Stats)
+		for _, db := range sum.Databases {
+			row("`"+db.Name+"`", db.Stats)
+			for _, s := range db.Schemas {
+				row("`"+db.Name+"."+s.Name+"`", s.Stats)
+			}
+		}
+	}
+	for i, c := range kept {
+		if i == 0 || c.Group() != kept[i-1].Group() {
+			fmt.Fprintf(w, "\n### `%s`\n\n", c.Group())
+		}
+		fmt.Fprintf(w, "- %s\n", c)
+	}
+	if len(suppressed) > 0 {
+		fmt.Fprintf(w, "\n<details><summary>🔕 Suppressed (%d)</summary>\n\n", len(suppressed))
+		for _, s := range suppressed {
+			fmt.Fprintf(w, "- %s  \n  ↳ %s\n", s.Change, s.Rule)
+		}
+		fmt.Fprintln(w, "\n</details>")
+	}
+	for _, r := range expired {
+		fmt.Fprintf(w, "\n⏰ Suppression expired, no longer applied: %s\n", r)
+	}
+}
+
+// printDefinitions shows what a check constraint or index change looks like before and after
+// normalization, to explain why it was (or wasn't) reported.
+func printDefinitions(w io.Writer, c Change) {
+	var oldDef, newDef string
+	var norm func(string) string
+	switch c.Object {
+	case ObjectCheck:
+		oldDef, _ = c.Old.(string)
+		newDef, _ = c.New.(string)
+		norm = normalize.Check
+	case ObjectIndex:
+		oldIdx, _ := c.Old.(models.Index)
+		newIdx, _ := c.New.(models.Index)
+		oldDef, newDef = oldIdx.Definition, newIdx.Definition
+		norm = normalize.IndexDef
+	default:
+		return
+	}
+	if c.Action != Changed && c.Action != Renamed {
+		return
+	}
+	fmt.Fprintf(w, "      raw:        %s\n                → %s\n", oldDef, newDef)
+	fmt.Fprintf(w, "      normalized: %s\n                → %s\n", norm(oldDef), norm(newDef))
+}
+
+// Compare returns every change needed to go from oldSnap to newSnap, sorted (see Sort).
+func Compare(oldSnap, newSnap *models.Snapshot, opts DiffOptions) []Change {
+	var changes []Change
+	add := func(c Change) { changes = append(changes, c) }
+
 	for db, newDB := range newSnap.Databases {
 		oldDB := oldSnap.Databases[db]
 
 		// Schemas
-		for schema := range newDB.Schemas {
+		for schema, s := range newDB.Schemas {
 			if _, ok := oldDB.Schemas[schema]; !ok {
-				fmt.Printf("✅ New schema: %s.%s\n", db, schema)
+				add(Change{Action: Added, Object: ObjectSchema, DB: db, Schema: schema, New: s})
 			}
 		}
-		for schema := range oldDB.Schemas {
+		for schema, s := range oldDB.Schemas {
 			if _, ok := newDB.Schemas[schema]; !ok {
-				fmt.Printf("❌ Schema dropped: %s.%s\n", db, schema)
+				add(Change{Action: Dropped, Object: ObjectSchema, DB: db, Schema: schema, Old: s})
 			}
 		}
 
@@ -28,114 +206,163 @@ func Diff(oldSnap, newSnap *models.Snapshot) {
 		for schema, newSchema := range newDB.Schemas {
 			oldSchema := oldDB.Schemas[schema]
 
-			for tbl := range newSchema.Tables {
+			for tbl, t := range newSchema.Tables {
 				if _, ok := oldSchema.Tables[tbl]; !ok {
-					fmt.Printf("✅ New table: %s.%s.%s\n", db, schema, tbl)
+					add(Change{Action: Added, Object: ObjectTable, DB: db, Schema: schema, Table: tbl, New: t})
 				}
 			}
-			for tbl := range oldSchema.Tables {
+			for tbl, t := range oldSchema.Tables {
 				if _, ok := newSchema.Tables[tbl]; !ok {
-					fmt.Printf("❌ Table dropped: %s.%s.%s\n", db, schema, tbl)
+					add(Change{Action: Dropped, Object: ObjectTable, DB: db, Schema: schema, Table: tbl, Old: t})
 				}
 			}
 
 			// Per-table details
 			for tbl, newTable := range newSchema.Tables {
-				oldTable := oldSchema.Tables[tbl]
-
-				// Columns
-				for col, dt := range newTable.Columns {
-					if _, ok := oldTable.Columns[col]; !ok {
-						fmt.Printf("✅ New column: %s.%s.%s.%s (%s)\n", db, schema, tbl, col, dt)
-					}
-				}
-				for col, oldT := range oldTable.Columns {
-					nt, ok := newTable.Columns[col]
-					if !ok {
-						fmt.Printf("❌ Column dropped: %s.%s.%s.%s\n", db, schema, tbl, col)
-					} else if nt != oldT {
-						fmt.Printf("⚠️ Type changed: %s.%s.%s.%s (%s → %s)\n", db, schema, tbl, col, oldT, nt)
-					}
+				for _, c := range compareTables(oldSchema.Tables[tbl], newTable, opts.MatchByDefinition) {
+					c.DB, c.Schema, c.Table = db, schema, tbl
+					add(c)
 				}
+			}
+		}
+	}
 
-				// Primary key
-				if !slices.Equal(oldTable.PrimaryKey, newTable.PrimaryKey) {
-					if len(oldTable.PrimaryKey) == 0 && len(newTable.PrimaryKey) > 0 {
-						fmt.Printf("✅ Primary key set: %s.%s.%s (%v)\n", db, schema, tbl, newTable.PrimaryKey)
-					} else if len(newTable.PrimaryKey) == 0 && len(oldTable.PrimaryKey) > 0 {
-						fmt.Printf("❌ Primary key dropped: %s.%s.%s (was %v)\n", db, schema, tbl, oldTable.PrimaryKey)
-					} else {
-						fmt.Printf("🔁 Primary key changed: %s.%s.%s (%v → %v)\n", db, schema, tbl, oldTable.PrimaryKey, newTable.PrimaryKey)
-					}
-				}
+	// a database only on the old side is one drop; its schemas and tables aren't listed
+	for db, oldDB := range oldSnap.Databases {
+		if _, ok := newSnap.Databases[db]; !ok {
+			add(Change{Action: Dropped, Object: ObjectDatabase, DB: db, Old: oldDB})
+		}
+	}
 
-				// Unique constraints
-				for name, cols := range newTable.UniqueConstraints {
-					if _, ok := oldTable.UniqueConstraints[name]; !ok {
-						fmt.Printf("✅ Unique constraint added: %s.%s.%s %s (%v)\n", db, schema, tbl, name, cols)
-					}
-				}
-				for name, oldCols := range oldTable.UniqueConstraints {
-					if newCols, ok := newTable.UniqueConstraints[name]; !ok {
-						fmt.Printf("❌ Unique constraint dropped: %s.%s.%s %s\n", db, schema, tbl, name)
-					} else if !slices.Equal(oldCols, newCols) {
-						fmt.Printf("🔁 Unique constraint changed: %s.%s.%s %s (%v → %v)\n", db, schema, tbl, name, oldCols, newCols)
-					}
-				}
+	if opts.DetectRenames || len(opts.RenameHints) > 0 {
+		changes = DetectRenames(changes, opts)
+	}
+	Sort(changes)
+	return changes
+}
 
-				// Check constraints
-				for name, def := range newTable.CheckConstraints {
-					if _, ok := oldTable.CheckConstraints[name]; !ok {
-						fmt.Printf("✅ Check constraint added: %s.%s.%s %s\n", db, schema, tbl, name)
-					} else if oldTable.CheckConstraints[name] != def {
-						fmt.Printf("🔁 Check constraint changed: %s.%s.%s %s\n", db, schema, tbl, name)
-					}
-				}
-				for name := range oldTable.CheckConstraints {
-					if _, ok := newTable.CheckConstraints[name]; !ok {
-						fmt.Printf("❌ Check constraint dropped: %s.%s.%s %s\n", db, schema, tbl, name)
-					}
-				}
+// compareTables returns the column, constraint and index changes of one table.
+// DB, Schema and Table are left for the caller to fill in.
+func compareTables(oldTable, newTable models.TableSnapshot, byDefinition bool) []Change {
+	var changes []Change
+	add := func(c Change) { changes = append(changes, c) }
 
-				// Foreign keys
-				for name, fk := range newTable.ForeignKeys {
-					if _, ok := oldTable.ForeignKeys[name]; !ok {
-						fmt.Printf("✅ Foreign key added: %s.%s.%s %s (%v → %s.%s %v)\n",
-							db, schema, tbl, name, fk.Columns, fk.RefSchema, fk.RefTable, fk.RefColumns)
-					}
-				}
-				for name, ofk := range oldTable.ForeignKeys {
-					if nfk, ok := newTable.ForeignKeys[name]; !ok {
-						fmt.Printf("❌ Foreign key dropped: %s.%s.%s %s\n", db, schema, tbl, name)
-					} else {
-						if !slices.Equal(ofk.Columns, nfk.Columns) ||
-							ofk.RefSchema != nfk.RefSchema ||
-							ofk.RefTable != nfk.RefTable ||
-							!slices.Equal(ofk.RefColumns, nfk.RefColumns) ||
-							ofk.UpdateRule != nfk.UpdateRule ||
-							ofk.DeleteRule != nfk.DeleteRule {
-							fmt.Printf("🔁 Foreign key changed: %s.%s.%s %s\n", db, schema, tbl, name)
-						}
-					}
-				}
+	// Columns
+	for col := range newTable.Columns {
+		if _, ok := oldTable.Columns[col]; !ok {
+			add(Change{Action: Added, Object: ObjectColumn, Name: col, New: newTable.ColumnType(col), Position: newTable.ColumnPositions[col]})
+		}
+	}
+	for col, oldDT := range oldTable.Columns {
+		newDT, ok := newTable.Columns[col]
+		if !ok {
+			add(Change{Action: Dropped, Object: ObjectColumn, Name: col, Old: oldTable.ColumnType(col), Position: oldTable.ColumnPositions[col]})
+			continue
+		}
+		// full types only when both snapshots have them, so older snapshots don't look changed
+		oldFull, oldOK := oldTable.ColumnTypes[col]
+		newFull, newOK := newTable.ColumnTypes[col]
+		if (oldOK && newOK && oldFull != newFull) || ((!oldOK || !newOK) && oldDT != newDT) {
+			add(columnChange(col, oldTable.ColumnType(col), newTable.ColumnType(col)))
+		}
+	}
 
-				// Indexes (by name)
-				for name, idx := range newTable.Indexes {
-					if _, ok := oldTable.Indexes[name]; !ok {
-						fmt.Printf("✅ Index added: %s.%s.%s %s (unique=%v cols=%v)\n", db, schema, tbl, name, idx.Unique, idx.Columns)
-					}
-				}
-				for name, oidx := range oldTable.Indexes {
-					if nidx, ok := newTable.Indexes[name]; !ok {
-						fmt.Printf("❌ Index dropped: %s.%s.%s %s\n", db, schema, tbl, name)
-					} else if oidx.Unique != nidx.Unique || oidx.Definition != nidx.Definition || !slices.Equal(oidx.Columns, nidx.Columns) {
-						fmt.Printf("🔁 Index changed: %s.%s.%s %s\n", db, schema, tbl, name)
-					}
-				}
+	// Primary key
+	if !slices.Equal(oldTable.PrimaryKey, newTable.PrimaryKey) {
+		if len(oldTable.PrimaryKey) == 0 && len(newTable.PrimaryKey) > 0 {
+			add(Change{Action: Added, Object: ObjectPrimaryKey, New: newTable.PrimaryKey})
+		} else if len(newTable.PrimaryKey) == 0 && len(oldTable.PrimaryKey) > 0 {
+			add(Change{Action: Dropped, Object: ObjectPrimaryKey, Old: oldTable.PrimaryKey})
+		} else {
+			add(Change{Action: Changed, Object: ObjectPrimaryKey, Old: oldTable.PrimaryKey, New: newTable.PrimaryKey})
+		}
+	}
 
-				// sanity: any unknown keys?
-				_ = maps.Clone(newTable.Columns) // keep compiler happy if using maps package
+	// Unique constraints, check constraints, foreign keys, indexes
+	changes = append(changes, compareNamed(ObjectUnique, oldTable.UniqueConstraints, newTable.UniqueConstraints, uniqueKey, byDefinition)...)
+	changes = append(changes, compareNamed(ObjectCheck, oldTable.CheckConstraints, newTable.CheckConstraints, checkKey, byDefinition)...)
+	changes = append(changes, compareNamed(ObjectForeignKey, oldTable.ForeignKeys, newTable.ForeignKeys, foreignKeyKey, byDefinition)...)
+	changes = append(changes, compareNamed(ObjectIndex, oldTable.Indexes, newTable.Indexes, indexKey, byDefinition)...)
+
+	return changes
+}
+
+// columnChange is a column type change, classified by typecompat.
+func columnChange(name, oldType, newType string) Change {
+	tc := typecompat.Classify(oldType, newType)
+	return Change{Action: Changed, Object: ObjectColumn, Name: name, Old: oldType, New: newType, TypeChange: &tc}
+}
+
+// compareNamed compares constraints or indexes of one type. Objects are matched by name, and
+// with byDefinition first by definition key, so a same-definition object under another name is a rename.
+func compareNamed[T any](obj Object, oldM, newM map[string]T, key func(T) string, byDefinition bool) []Change {
+	var changes []Change
+	renamedOld := map[string]bool{}
+	renamedNew := map[string]bool{}
+
+	if byDefinition {
+		unchanged := func(name string) bool {
+			o, inOld := oldM[name]
+			n, inNew := newM[name]
+			return inOld && inNew && key(o) == key(n)
+		}
+		// sorted so that pairing among identical definitions is deterministic
+		oldByKey := map[string][]string{}
+		for _, name := range slices.Sorted(maps.Keys(oldM)) {
+			if !unchanged(name) {
+				k := key(oldM[name])
+				oldByKey[k] = append(oldByKey[k], name)
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(newM)) {
+			if unchanged(name) {
+				continue
 			}
+			k := key(newM[name])
+			if cands := oldByKey[k]; len(cands) > 0 {
+				oldName := cands[0]
+				oldByKey[k] = cands[1:]
+				renamedOld[oldName], renamedNew[name] = true, true
+				changes = append(changes, Change{
+					Action: Renamed, Object: obj, Name: name, OldName: oldName,
+					Old: oldM[oldName], New: newM[name], Confidence: 1, ByDefinition: true,
+				})
+			}
+		}
+	}
+
+	for name, v := range newM {
+		if renamedNew[name] {
+			continue
+		}
+		if _, ok := oldM[name]; !ok || renamedOld[name] {
+			changes = append(changes, Change{Action: Added, Object: obj, Name: name, New: v})
+		}
+	}
+	for name, ov := range oldM {
+		if renamedOld[name] {
+			continue
+		}
+		if nv, ok := newM[name]; !ok || renamedNew[name] {
+			changes = append(changes, Change{Action: Dropped, Object: obj, Name: name, Old: ov})
+		} else if key(ov) != key(nv) {
+			changes = append(changes, Change{Action: Changed, Object: obj, Name: name, Old: ov, New: nv})
 		}
 	}
+	return changes
+}
+
+// Definition keys: two objects with equal keys are the same apart from their names.
+
+func uniqueKey(cols []string) string { return strings.Join(cols, ",") }
+
+func checkKey(clause string) string { return normalize.Check(clause) }
+
+func foreignKeyKey(fk models.ForeignKey) string {
+	return fmt.Sprintf("(%s) -> %s.%s(%s) ON UPDATE %s ON DELETE %s",
+		strings.Join(fk.Columns, ","), fk.RefSchema, fk.RefTable, strings.Join(fk.RefColumns, ","), fk.UpdateRule, fk.DeleteRule)
+}
+
+func indexKey(idx models.Index) string {
+	return fmt.Sprintf("unique=%v %s", idx.Unique, indexBody(normalize.IndexDef(idx.Definition)))
 }
@@ -0,0 +1,112 @@
+package snapshot
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/models/modelstest"
+)
+
+func twoDatabases(crm bool, usersCols, ordersCols map[string]string) *models.Snapshot {
+	snap := &models.Snapshot{Databases: map[string]models.DatabaseSnapshot{
+		"app": {DBName: "app", Schemas: map[string]models.SchemaSnapshot{
+			"public": {Name: "public", Tables: map[string]models.TableSnapshot{
+				"users":  {Name: "users", Columns: usersCols},
+				"orders": {Name: "orders", Columns: ordersCols},
+			}},
+			"audit": {Name: "audit", Tables: map[string]models.TableSnapshot{
+				"log": {Name: "log", Columns: map[string]string{"id": "bigint"}},
+			}},
+		}},
+	}}
+	if crm {
+		snap.Databases["crm"] = models.DatabaseSnapshot{DBName: "crm", Schemas: map[string]models.SchemaSnapshot{
+			"public": {Name: "public", Tables: map[string]models.TableSnapshot{"leads": {Name: "leads", Columns: map[string]string{"id": "integer"}}}},
+		}}
+	}
+	return snap
+}
+
+func TestFprintDeterministic(t *testing.T) {
+	oldSnap := twoDatabases(false,
+		map[string]string{"id": "integer", "name_a": "text", "name_b": "text", "c": "text"},
+		map[string]string{"id": "integer", "x": "text", "y": "integer"})
+	newSnap := twoDatabases(true,
+		map[string]string{"id": "bigint", "name_c": "text", "name_d": "text", "f": "integer"},
+		map[string]string{"id": "bigint", "z": "boolean", "y": "bigint"})
+	opts := DiffOptions{DetectRenames: true}
+
+	render := func() string {
+		var b bytes.Buffer
+		Fprint(&b, Compare(oldSnap, newSnap, opts), opts)
+		return b.String()
+	}
+	first := render()
+	for i := 0; i < 50; i++ {
+		if got := render(); got != first {
+			t.Fatalf("run %d differs:\n%s\nfirst:\n%s", i, got, first)
+		}
+	}
+
+	// every table's changes sit under a single heading
+	var headings []string
+	for _, line := range strings.Split(first, "\n") {
+		if h, ok := strings.CutPrefix(line, "📂 "); ok {
+			headings = append(headings, h)
+		}
+	}
+	if want := "app.public.orders app.public.users crm.public crm.public.leads"; strings.Join(headings, " ") != want {
+		t.Errorf("headings = %v, want %s", headings, want)
+	}
+}
+
+func TestCompareDroppedDatabase(t *testing.T) {
+	cols := map[string]string{"id": "integer"}
+	withCRM, withoutCRM := twoDatabases(true, cols, cols), twoDatabases(false, cols, cols)
+
+	changes := Compare(withCRM, withoutCRM, DiffOptions{})
+	if len(changes) != 1 || changes[0].Object != ObjectDatabase || changes[0].Action != Dropped {
+		t.Fatalf("changes = %v, want only the crm database dropped", changes)
+	}
+	if got, want := changes[0].String(), "❌ Database dropped: crm"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+	if got := changes[0].Path(); got != "crm" {
+		t.Errorf("Path = %q, want crm", got)
+	}
+
+	// undone, the database comes back the way Compare lists a new one
+	got := Invert(changes)
+	Sort(got)
+	want := Compare(withoutCRM, withCRM, DiffOptions{})
+	if len(got) != len(want) {
+		t.Fatalf("Invert:\n%v\nwant:\n%v", got, want)
+	}
+	for i := range got {
+		if got[i].String() != want[i].String() {
+			t.Errorf("Invert[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMatchByDefinition(t *testing.T) {
+	withCheck := func(name, clause string) *models.Snapshot {
+		tbl := modelstest.Table("users", "id")
+		tbl.CheckConstraints = map[string]string{name: clause}
+		return modelstest.Snapshot(tbl)
+	}
+	oldSnap := withCheck("users_id_check", "id > 0")
+	newSnap := withCheck("users_id_positive", "(id > 0)")
+
+	got := Compare(oldSnap, newSnap, DiffOptions{MatchByDefinition: true})
+	if len(got) != 1 || got[0].Action != Renamed || got[0].OldName != "users_id_check" || got[0].Name != "users_id_positive" || !got[0].ByDefinition {
+		t.Errorf("changes = %v, want one rename by definition", got)
+	}
+
+	// by name only it is a drop and an add
+	if got := Compare(oldSnap, newSnap, DiffOptions{}); len(got) != 2 {
+		t.Errorf("changes without MatchByDefinition = %v, want a drop and an add", got)
+	}
+}
@@ -0,0 +1,140 @@
+package snapshot
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+)
+
+// Mapping aligns databases and schemas whose names differ between the two sides of a comparison,
+// e.g. app_dev on the old side is app on the new side. In a mapping file:
+//
+//	databases:
+//	  app_dev: app                      # old name: new name
+//	schemas:
+//	  tenant_a: tenant_template         # in every database
+//	  app_dev.audit_v2: audit           # in one database (old database name)
+type Mapping struct {
+	Databases map[string]string `yaml:"databases"` // old name -> new name
+	Schemas   map[string]string `yaml:"schemas"`   // old "schema" or "db.schema" -> new schema name
+}
+
+// LoadMapping reads a mapping file.
+func LoadMapping(path string) (Mapping, error) {
+	var m Mapping
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("%s: %w", path, err)
+	}
+	for key := range m.Schemas {
+		if strings.Count(key, ".") > 1 {
+			return m, fmt.Errorf("%s: schemas: want <schema> or <db>.<schema>, got %q", path, key)
+		}
+	}
+	return m, nil
+}
+
+// Merge returns m with the entries of o added; o wins on conflicts.
+func (m Mapping) Merge(o Mapping) Mapping {
+	out := Mapping{Databases: map[string]string{}, Schemas: map[string]string{}}
+	for _, src := range []Mapping{m, o} {
+		maps.Copy(out.Databases, src.Databases)
+		maps.Copy(out.Schemas, src.Schemas)
+	}
+	return out
+}
+
+// Apply renames the databases and schemas of the old snapshot to their new-side names,
+// including the schemas foreign keys refer to.
+func (m Mapping) Apply(old *models.Snapshot) error {
+	renamed := map[string]models.DatabaseSnapshot{}
+	for _, name := range slices.Sorted(maps.Keys(old.Databases)) {
+		db, err := m.applySchemas(name, old.Databases[name])
+		if err != nil {
+			return err
+		}
+		to := name
+		if t, ok := m.Databases[name]; ok {
+			to = t
+		}
+		if _, dup := renamed[to]; dup {
+			return fmt.Errorf("snapshot: mapping: more than one database maps to %q", to)
+		}
+		db.DBName = to
+		renamed[to] = db
+	}
+	old.Databases = renamed
+	return nil
+}
+
+// schema returns the new name of a schema of database db.
+func (m Mapping) schema(db, schema string) string {
+	if to, ok := m.Schemas[db+"."+schema]; ok {
+		return to
+	}
+	if to, ok := m.Schemas[schema]; ok {
+		return to
+	}
+	return schema
+}
+
+func (m Mapping) applySchemas(dbName string, db models.DatabaseSnapshot) (models.DatabaseSnapshot, error) {
+	if len(m.Schemas) == 0 {
+		return db, nil
+	}
+	schemas := map[string]models.SchemaSnapshot{}
+	for _, name := range slices.Sorted(maps.Keys(db.Schemas)) {
+		s := db.Schemas[name]
+		to := m.schema(dbName, name)
+		if _, dup := schemas[to]; dup {
+			return db, fmt.Errorf("snapshot: mapping: more than one schema of %s maps to %q", dbName, to)
+		}
+		s.Name = to
+		tables := map[string]models.TableSnapshot{}
+		for tn, t := range s.Tables {
+			if len(t.ForeignKeys) > 0 {
+				fks := map[string]models.ForeignKey{}
+				for fn, fk := range t.ForeignKeys {
+					fk.RefSchema = m.schema(dbName, fk.RefSchema)
+					fks[fn] = fk
+				}
+				t.ForeignKeys = fks
+			}
+			tables[tn] = t
+		}
+		s.Tables = tables
+		schemas[to] = s
+	}
+	db.Schemas = schemas
+	return db, nil
+}
+
+// PairSingle maps the only database of old to the only database of new when their names
+// differ, the common case of one app database per environment. It returns false otherwise.
+func (m *Mapping) PairSingle(old, new *models.Snapshot) bool {
+	if len(old.Databases) != 1 || len(new.Databases) != 1 {
+		return false
+	}
+	o := slices.Collect(maps.Keys(old.Databases))[0]
+	n := slices.Collect(maps.Keys(new.Databases))[0]
+	if o == n {
+		return false
+	}
+	if _, ok := m.Databases[o]; ok {
+		return false
+	}
+	if m.Databases == nil {
+		m.Databases = map[string]string{}
+	}
+	m.Databases[o] = n
+	return true
+}
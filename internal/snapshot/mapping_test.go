@@ -0,0 +1,108 @@
+package snapshot
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+)
+
+func schemas(db string, names ...string) *models.Snapshot {
+	ss := map[string]models.SchemaSnapshot{}
+	for _, n := range names {
+		ss[n] = models.SchemaSnapshot{Name: n, Tables: map[string]models.TableSnapshot{
+			"orders": {Name: "orders", Columns: map[string]string{"id": "integer"}, ForeignKeys: map[string]models.ForeignKey{
+				"fk": {Name: "fk", Columns: []string{"id"}, RefSchema: n, RefTable: "users", RefColumns: []string{"id"}},
+			}},
+		}}
+	}
+	return &models.Snapshot{Databases: map[string]models.DatabaseSnapshot{db: {DBName: db, Schemas: ss}}}
+}
+
+func TestMappingApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       Mapping
+		snap    *models.Snapshot
+		want    string // db.schema pairs after Apply
+		wantErr string
+	}{
+		{"database", Mapping{Databases: map[string]string{"app_dev": "app"}}, schemas("app_dev", "public"), "app.public", ""},
+		{"schema everywhere", Mapping{Schemas: map[string]string{"tenant_a": "tenant"}}, schemas("app", "tenant_a", "public"), "app.public app.tenant", ""},
+		{"schema in one database", Mapping{Schemas: map[string]string{"app_dev.v2": "audit", "v2": "other"}}, schemas("app_dev", "v2"), "app_dev.audit", ""},
+		{"schemas collide", Mapping{Schemas: map[string]string{"a": "b"}}, schemas("app", "a", "b"), "", `more than one schema of app maps to "b"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.m.Apply(tt.snap)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, db := range slices.Sorted(maps.Keys(tt.snap.Databases)) {
+				for _, name := range slices.Sorted(maps.Keys(tt.snap.Databases[db].Schemas)) {
+					s := tt.snap.Databases[db].Schemas[name]
+					got = append(got, db+"."+s.Name)
+					if fk := s.Tables["orders"].ForeignKeys["fk"]; fk.RefSchema != s.Name {
+						t.Errorf("%s.%s: foreign key refers to schema %q", db, s.Name, fk.RefSchema)
+					}
+				}
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("schemas = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPairSingle(t *testing.T) {
+	var m Mapping
+	if !m.PairSingle(schemas("app_dev", "public"), schemas("app", "public")) || m.Databases["app_dev"] != "app" {
+		t.Errorf("PairSingle didn't map app_dev to app: %+v", m)
+	}
+	if (&Mapping{}).PairSingle(schemas("app", "public"), schemas("app", "public")) {
+		t.Error("PairSingle paired databases of the same name")
+	}
+	explicit := Mapping{Databases: map[string]string{"app_dev": "other"}}
+	if explicit.PairSingle(schemas("app_dev", "public"), schemas("app", "public")) || explicit.Databases["app_dev"] != "other" {
+		t.Errorf("PairSingle overrode an explicit mapping: %+v", explicit)
+	}
+}
+
+// Two configs with one database each are compared database to database, whatever their names.
+func TestCompareAfterPairSingle(t *testing.T) {
+	left, right := schemas("app_dev", "public"), schemas("app", "public")
+	right.Databases["app"].Schemas["public"].Tables["orders"].Columns["total"] = "numeric"
+
+	var m Mapping
+	if !m.PairSingle(left, right) {
+		t.Fatal("PairSingle didn't pair app_dev with app")
+	}
+	if err := m.Apply(left); err != nil {
+		t.Fatal(err)
+	}
+	got := Compare(left, right, DiffOptions{})
+	if len(got) != 1 || got[0].Action != Added || got[0].Path() != "app.public.orders.total" {
+		t.Errorf("changes = %v, want only app.public.orders.total added", got)
+	}
+}
+
+func TestLoadMapping(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.yaml")
+	if err := os.WriteFile(path, []byte("databases:\n  app_dev: app\nschemas:\n  a.b.c: d\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadMapping(path); err == nil || !strings.Contains(err.Error(), `want <schema> or <db>.<schema>, got "a.b.c"`) {
+		t.Errorf("err = %v", err)
+	}
+}
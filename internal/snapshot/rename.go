@@ -0,0 +1,356 @@
+package snapshot
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+)
+
+// minRenameConfidence is the lowest heuristic score reported as a rename rather than a drop plus an add.
+const minRenameConfidence = 0.5
+
+// minColumnNameSimilarity is the name similarity a column rename needs when its position doesn't
+// match: a shared type alone says nothing, as most columns are text or integer.
+const minColumnNameSimilarity = 0.5
+
+// RenameHint confirms that a dropped object was renamed. One per line in a hints file:
+//
+//	# <object> <old path> -> <new name>
+//	table  app.public.legacy -> legacy_data
+//	column app.public.users.nick -> nickname
+//	index  app.public.users.users_email_key -> users_email_key1
+//
+// Confirmed renames are reported with confidence 1 even when the heuristics don't match them,
+// e.g. a column that was renamed and retyped.
+type RenameHint struct {
+	Object Object
+	From   string // dotted path of the old object, as Change.Path
+	To     string // new table/column/constraint/index name
+
+	Source string // file:line the hint came from
+}
+
+// LoadRenameHints parses a rename hints file.
+func LoadRenameHints(path string) ([]RenameHint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var hints []RenameHint
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 4 || fields[2] != "->" {
+			return nil, fmt.Errorf("%s:%d: want <object> <old path> -> <new name>", path, n)
+		}
+		obj := Object(fields[0])
+		switch obj {
+		case ObjectTable, ObjectColumn, ObjectUnique, ObjectCheck, ObjectForeignKey, ObjectIndex:
+		default:
+			return nil, fmt.Errorf("%s:%d: can't rename object type %q", path, n, fields[0])
+		}
+		hints = append(hints, RenameHint{Object: obj, From: fields[1], To: fields[3], Source: fmt.Sprintf("%s:%d", path, n)})
+	}
+	return hints, sc.Err()
+}
+
+// DetectRenames replaces drop+add pairs that look like renames with a single Renamed change.
+//
+// Tables match on identical columns, columns on identical type, and constraints and indexes on
+// identical definitions. Among candidates, similar names score higher; pairs below
+// minRenameConfidence are left as drop+add. Hints confirm renames regardless of the heuristics.
+// A renamed table is compared with its old self, so only its real column/constraint changes remain.
+func DetectRenames(changes []Change, opts DiffOptions) []Change {
+	hints := opts.RenameHints
+	changes = pairRenames(changes, hints, func(c Change) bool { return c.Object == ObjectTable })
+
+	// re-diff the contents of renamed tables instead of listing everything in them as new
+	var out []Change
+	renamed := map[string]Change{} // new table path -> rename
+	for _, c := range changes {
+		if c.Object == ObjectTable && c.Action == Renamed {
+			renamed[tablePath(c)] = c
+		}
+	}
+	for _, c := range changes {
+		if _, ok := renamed[tablePath(c)]; ok && c.Object != ObjectTable {
+			continue
+		}
+		out = append(out, c)
+		if c.Object == ObjectTable && c.Action == Renamed {
+			oldT, _ := c.Old.(models.TableSnapshot)
+			newT, _ := c.New.(models.TableSnapshot)
+			for _, tc := range compareTables(oldT, newT, opts.MatchByDefinition) {
+				tc.DB, tc.Schema, tc.Table = c.DB, c.Schema, c.Table
+				out = append(out, tc)
+			}
+		}
+	}
+
+	return pairRenames(out, hints, func(c Change) bool {
+		return c.Object != ObjectTable && c.Object != ObjectSchema && c.Object != ObjectPrimaryKey
+	})
+}
+
+// pairRenames pairs dropped and added changes selected by eligible within the same parent object.
+func pairRenames(changes []Change, hints []RenameHint, eligible func(Change) bool) []Change {
+	type candidate struct {
+		drop, add int
+		score     float64
+		confirmed bool
+	}
+
+	// index dropped/added changes by object type + parent path
+	dropped := map[string][]int{}
+	added := map[string][]int{}
+	for i, c := range changes {
+		if !eligible(c) {
+			continue
+		}
+		key := string(c.Object) + " " + parentPath(c)
+		switch c.Action {
+		case Dropped:
+			dropped[key] = append(dropped[key], i)
+		case Added:
+			added[key] = append(added[key], i)
+		}
+	}
+
+	var cands []candidate
+	for _, key := range slices.Sorted(maps.Keys(dropped)) {
+		drops, adds := dropped[key], added[key]
+		for _, d := range drops {
+			// how many adds could this drop be, and vice versa: unambiguous pairs score higher
+			var matching []int
+			for _, a := range adds {
+				if sameShape(changes[d], changes[a]) {
+					matching = append(matching, a)
+				}
+			}
+			for _, a := range adds {
+				if hinted(hints, changes[d], changes[a]) {
+					cands = append(cands, candidate{drop: d, add: a, score: 1, confirmed: true})
+					continue
+				}
+				if !slices.Contains(matching, a) {
+					continue
+				}
+				unique := len(matching) == 1 && countShape(changes, drops, changes[a]) == 1
+				score := renameScore(changes[d], changes[a], unique)
+				if score >= minRenameConfidence {
+					cands = append(cands, candidate{drop: d, add: a, score: score})
+				}
+			}
+		}
+	}
+
+	// greedy: best (confirmed first) pairs win, each change is used once; ties go by
+	// drop path, then add path, so equal-score pairings don't depend on map order
+	sort.SliceStable(cands, func(i, j int) bool {
+		if cands[i].confirmed != cands[j].confirmed {
+			return cands[i].confirmed
+		}
+		if cands[i].score != cands[j].score {
+			return cands[i].score > cands[j].score
+		}
+		if di, dj := changes[cands[i].drop].Path(), changes[cands[j].drop].Path(); di != dj {
+			return di < dj
+		}
+		return changes[cands[i].add].Path() < changes[cands[j].add].Path()
+	})
+	used := map[int]bool{}
+	replace := map[int][]Change{} // index of the add -> changes to emit there
+	for _, p := range cands {
+		if used[p.drop] || used[p.add] {
+			continue
+		}
+		used[p.drop], used[p.add] = true, true
+
+		d, a := changes[p.drop], changes[p.add]
+		r := a
+		r.Action = Renamed
+		r.Old = d.Old
+		r.OldName = d.Name
+		if d.Object == ObjectTable {
+			r.OldName = d.Table
+		}
+		r.Confidence = round2(p.score)
+		r.Confirmed = p.confirmed
+		replace[p.add] = append(replace[p.add], r)
+
+		// a confirmed rename may also have changed shape, e.g. a column that was renamed and retyped
+		if !sameShape(d, a) && d.Object != ObjectTable {
+			ch := a
+			ch.Action, ch.Old = Changed, d.Old
+			if d.Object == ObjectColumn {
+				oldType, _ := d.Old.(string)
+				newType, _ := a.New.(string)
+				ch = columnChange(a.Name, oldType, newType)
+				ch.DB, ch.Schema, ch.Table = a.DB, a.Schema, a.Table
+			}
+			replace[p.add] = append(replace[p.add], ch)
+		}
+	}
+
+	var out []Change
+	for i, c := range changes {
+		if rs, ok := replace[i]; ok {
+			out = append(out, rs...)
+		} else if !used[i] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// sameShape reports whether a dropped and an added object are identical apart from their names.
+func sameShape(d, a Change) bool {
+	switch d.Object {
+	case ObjectTable:
+		oldT, _ := d.Old.(models.TableSnapshot)
+		newT, _ := a.New.(models.TableSnapshot)
+		return len(oldT.Columns) > 0 && maps.Equal(oldT.Columns, newT.Columns)
+	case ObjectColumn:
+		return d.Old == a.New
+	case ObjectCheck:
+		oldClause, _ := d.Old.(string)
+		newClause, _ := a.New.(string)
+		return checkKey(oldClause) == checkKey(newClause)
+	case ObjectUnique:
+		oldCols, _ := d.Old.([]string)
+		newCols, _ := a.New.([]string)
+		return uniqueKey(oldCols) == uniqueKey(newCols)
+	case ObjectForeignKey:
+		oldFK, _ := d.Old.(models.ForeignKey)
+		newFK, _ := a.New.(models.ForeignKey)
+		return foreignKeyKey(oldFK) == foreignKeyKey(newFK)
+	case ObjectIndex:
+		oldIdx, _ := d.Old.(models.Index)
+		newIdx, _ := a.New.(models.Index)
+		return indexKey(oldIdx) == indexKey(newIdx)
+	}
+	return false
+}
+
+// countShape counts the drops in idxs with the same shape as the added change a.
+func countShape(changes []Change, idxs []int, a Change) int {
+	n := 0
+	for _, i := range idxs {
+		if sameShape(changes[i], a) {
+			n++
+		}
+	}
+	return n
+}
+
+// renameScore rates how likely a same-shape drop+add is a rename, in [0, 1].
+func renameScore(d, a Change, unique bool) float64 {
+	oldName, newName := d.Name, a.Name
+	if d.Object == ObjectTable {
+		oldName, newName = d.Table, a.Table
+	}
+	sim := similarity(oldName, newName)
+
+	switch d.Object {
+	case ObjectColumn:
+		// a type is weak evidence on its own; the same position is strong, as
+		// PostgreSQL never reuses a dropped column's attnum
+		samePosition := d.Position > 0 && d.Position == a.Position
+		if !samePosition && sim < minColumnNameSimilarity {
+			return 0
+		}
+		score := 0.4 + 0.3*sim
+		if unique {
+			score += 0.3
+		}
+		if samePosition {
+			score = min(1, score+0.3)
+		}
+		return score
+	case ObjectTable:
+		score := 0.6 + 0.2*sim
+		if unique {
+			score += 0.2
+		}
+		return score
+	default:
+		// identical constraint/index definitions are strong evidence
+		score := 0.8 + 0.1*sim
+		if unique {
+			score += 0.1
+		}
+		return score
+	}
+}
+
+func hinted(hints []RenameHint, d, a Change) bool {
+	newName := a.Name
+	if a.Object == ObjectTable {
+		newName = a.Table
+	}
+	for _, h := range hints {
+		if h.Object == d.Object && h.From == d.Path() && h.To == newName {
+			return true
+		}
+	}
+	return false
+}
+
+// similarity is the longest-common-subsequence ratio of two names, in [0, 1].
+func similarity(a, b string) float64 {
+	if a == "" && b == "" {
+		return 1
+	}
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			switch {
+			case a[i-1] == b[j-1]:
+				cur[j] = prev[j-1] + 1
+			case prev[j] > cur[j-1]:
+				cur[j] = prev[j]
+			default:
+				cur[j] = cur[j-1]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return 2 * float64(prev[len(b)]) / float64(len(a)+len(b))
+}
+
+// indexHeadRe matches the part of an indexdef that names the index and its table.
+var indexHeadRe = regexp.MustCompile(`(?i)^CREATE\s+(UNIQUE\s+)?INDEX\s+(CONCURRENTLY\s+)?\S+\s+ON\s+(ONLY\s+)?\S+\s*`)
+
+// indexBody strips the index and table names from an indexdef, e.g. "USING btree (email)".
+func indexBody(def string) string {
+	return indexHeadRe.ReplaceAllString(strings.TrimSpace(def), "")
+}
+
+func parentPath(c Change) string {
+	if c.Object == ObjectTable {
+		return c.DB + "." + c.Schema
+	}
+	return tablePath(c)
+}
+
+func tablePath(c Change) string {
+	return c.DB + "." + c.Schema + "." + c.Table
+}
+
+func round2(f float64) float64 {
+	return float64(int(f*100+0.5)) / 100
+}
@@ -0,0 +1,69 @@
+package snapshot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/models/modelstest"
+)
+
+func TestDetectRenamesDeterministic(t *testing.T) {
+	oldSnap := modelstest.Snapshot(modelstest.Table("t", "id", "name_a", "name_b", "x_a", "x_b"))
+	newSnap := modelstest.Snapshot(modelstest.Table("t", "id", "name_c", "name_d", "x_c", "x_d"))
+	opts := DiffOptions{DetectRenames: true}
+
+	first := Compare(oldSnap, newSnap, opts)
+	for i := 0; i < 50; i++ {
+		if got := Compare(oldSnap, newSnap, opts); !reflect.DeepEqual(got, first) {
+			t.Fatalf("run %d differs:\n%v\nfirst:\n%v", i, got, first)
+		}
+	}
+
+	want := map[string]string{"name_c": "name_a", "name_d": "name_b", "x_c": "x_a", "x_d": "x_b"}
+	renamed := map[string]string{}
+	for _, c := range first {
+		if c.Action == Renamed {
+			renamed[c.Name] = c.OldName
+		}
+	}
+	if !reflect.DeepEqual(renamed, want) {
+		t.Errorf("renames = %v, want %v", renamed, want)
+	}
+}
+
+func TestDetectRenames(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new models.TableSnapshot
+		want     map[string]string // new name -> old name
+	}{
+		{"single column", modelstest.Table("t", "id", "nick"), modelstest.Table("t", "id", "nickname"), map[string]string{"nickname": "nick"}},
+		{"different type is no rename",
+			models.TableSnapshot{Name: "t", Columns: map[string]string{"a": "text"}},
+			models.TableSnapshot{Name: "t", Columns: map[string]string{"b": "integer"}},
+			map[string]string{}},
+		{"unrelated names are no rename", modelstest.Table("t", "id", "legacy_flag"), modelstest.Table("t", "id", "created_by"), map[string]string{}},
+		{"unrelated names at other positions are no rename",
+			models.TableSnapshot{Name: "t", Columns: map[string]string{"id": "integer", "legacy_flag": "text"}, ColumnPositions: map[string]int{"id": 1, "legacy_flag": 2}},
+			models.TableSnapshot{Name: "t", Columns: map[string]string{"id": "integer", "created_by": "text"}, ColumnPositions: map[string]int{"id": 1, "created_by": 3}},
+			map[string]string{}},
+		{"unrelated names at the same position are a rename",
+			models.TableSnapshot{Name: "t", Columns: map[string]string{"id": "integer", "legacy_flag": "text"}, ColumnPositions: map[string]int{"id": 1, "legacy_flag": 2}},
+			models.TableSnapshot{Name: "t", Columns: map[string]string{"id": "integer", "created_by": "text"}, ColumnPositions: map[string]int{"id": 1, "created_by": 2}},
+			map[string]string{"created_by": "legacy_flag"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]string{}
+			for _, c := range Compare(modelstest.Snapshot(tt.old), modelstest.Snapshot(tt.new), DiffOptions{DetectRenames: true}) {
+				if c.Action == Renamed {
+					got[c.Name] = c.OldName
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("renames = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package snapshot
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Saba101/GoMetaSync/internal/typecompat"
+)
+
+// Breaking reports whether the change can break applications or writes that worked before:
+// anything dropped, table and column renames, column type changes that aren't widening or that
+// change the generated Go type, and new or changed constraints, which can reject rows that used to be accepted.
+func (c Change) Breaking() bool {
+	switch c.Action {
+	case Dropped:
+		return true
+	case Renamed:
+		return c.Object == ObjectTable || c.Object == ObjectColumn
+	case Changed:
+		if c.TypeChange != nil {
+			return c.TypeChange.Class != typecompat.Widening || c.TypeChange.GoTypeChanged
+		}
+		return c.Object != ObjectIndex
+	case Added:
+		switch c.Object {
+		case ObjectPrimaryKey, ObjectUnique, ObjectCheck, ObjectForeignKey:
+			return true
+		}
+	}
+	return false
+}
+
+// Counts counts changes of one object type by action.
+type Counts struct {
+	Added   int `json:"added"`
+	Dropped int `json:"dropped"`
+	Changed int `json:"changed"`
+	Renamed int `json:"renamed"`
+}
+
+// Stats summarizes a set of changes.
+type Stats struct {
+	Changes       int                `json:"changes"`
+	Breaking      int                `json:"breaking"`
+	NonBreaking   int                `json:"non_breaking"`
+	TablesTouched int                `json:"tables_touched"`
+	Objects       map[Object]*Counts `json:"objects"`
+
+	tables map[string]bool
+}
+
+// SchemaSummary is the summary of one schema.
+type SchemaSummary struct {
+	Name string `json:"name"`
+	Stats
+}
+
+// DatabaseSummary is the summary of one database and its schemas.
+type DatabaseSummary struct {
+	Name string `json:"name"`
+	Stats
+	Schemas []SchemaSummary `json:"schemas"`
+}
+
+// Summary gives an overview of a diff: counts per object type and action, breaking vs
+// non-breaking changes and tables touched, in total and per database and schema.
+type Summary struct {
+	Stats
+	Databases []DatabaseSummary `json:"databases"`
+}
+
+// Summarize counts changes. Constraints of new tables count as non-breaking, as nothing used them yet.
+func Summarize(changes []Change) Summary {
+	created := map[string]bool{}
+	for _, c := range changes {
+		if c.Action == Added && (c.Object == ObjectTable || c.Object == ObjectSchema) {
+			created[c.Group()] = true
+		}
+	}
+
+	var sum Summary
+	for _, c := range changes {
+		breaking := c.Breaking() && !created[c.Group()] && !created[c.DB+"."+c.Schema]
+		sum.add(c, breaking)
+
+		i := slices.IndexFunc(sum.Databases, func(d DatabaseSummary) bool { return d.Name == c.DB })
+		if i < 0 {
+			sum.Databases = append(sum.Databases, DatabaseSummary{Name: c.DB})
+			i = len(sum.Databases) - 1
+		}
+		db := &sum.Databases[i]
+		db.add(c, breaking)
+
+		if c.Schema == "" {
+			continue // a database drop
+		}
+		j := slices.IndexFunc(db.Schemas, func(s SchemaSummary) bool { return s.Name == c.Schema })
+		if j < 0 {
+			db.Schemas = append(db.Schemas, SchemaSummary{Name: c.Schema})
+			j = len(db.Schemas) - 1
+		}
+		db.Schemas[j].add(c, breaking)
+	}
+	slices.SortFunc(sum.Databases, func(a, b DatabaseSummary) int { return strings.Compare(a.Name, b.Name) })
+	for _, db := range sum.Databases {
+		slices.SortFunc(db.Schemas, func(a, b SchemaSummary) int { return strings.Compare(a.Name, b.Name) })
+	}
+	return sum
+}
+
+func (s *Stats) add(c Change, breaking bool) {
+	if s.Objects == nil {
+		s.Objects = map[Object]*Counts{}
+		s.tables = map[string]bool{}
+	}
+	s.Changes++
+	if breaking {
+		s.Breaking++
+	} else {
+		s.NonBreaking++
+	}
+	if c.Table != "" && !s.tables[tablePath(c)] {
+		s.tables[tablePath(c)] = true
+		s.TablesTouched++
+	}
+
+	n := s.Objects[c.Object]
+	if n == nil {
+		n = &Counts{}
+		s.Objects[c.Object] = n
+	}
+	switch c.Action {
+	case Added:
+		n.Added++
+	case Dropped:
+		n.Dropped++
+	case Changed:
+		n.Changed++
+	case Renamed:
+		n.Renamed++
+	}
+}
+
+// String renders the totals on one line, e.g.
+// "12 changes (3 breaking, 9 non-breaking), 4 tables touched".
+func (s Stats) String() string {
+	return fmt.Sprintf("%d changes (%d breaking, %d non-breaking), %d tables touched",
+		s.Changes, s.Breaking, s.NonBreaking, s.TablesTouched)
+}
+
+// ObjectCounts renders the per-object counts, e.g. "column +2 -1 ~1, index ✏️1".
+func (s Stats) ObjectCounts() string {
+	var parts []string
+	for _, obj := range Objects {
+		n := s.Objects[obj]
+		if n == nil {
+			continue
+		}
+		var p []string
+		for _, v := range []struct {
+			sym string
+			n   int
+		}{{"+", n.Added}, {"-", n.Dropped}, {"~", n.Changed}, {"✏️", n.Renamed}} {
+			if v.n > 0 {
+				p = append(p, fmt.Sprintf("%s%d", v.sym, v.n))
+			}
+		}
+		parts = append(parts, string(obj)+" "+strings.Join(p, " "))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,79 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/typecompat"
+)
+
+func TestBreaking(t *testing.T) {
+	col := Change{Object: ObjectColumn, DB: "app", Schema: "public", Table: "users", Name: "email"}
+	with := func(c Change, a Action) Change { c.Action = a; return c }
+	typeChange := func(class typecompat.Class, goTypeChanged bool) Change {
+		c := with(col, Changed)
+		c.TypeChange = &typecompat.Result{Class: class, GoTypeChanged: goTypeChanged}
+		return c
+	}
+
+	tests := []struct {
+		name string
+		c    Change
+		want bool
+	}{
+		{"column added", with(col, Added), false},
+		{"column dropped", with(col, Dropped), true},
+		{"column renamed", with(col, Renamed), true},
+		{"widening", typeChange(typecompat.Widening, false), false},
+		{"widening changing the Go type", typeChange(typecompat.Widening, true), true},
+		{"rewrite", typeChange(typecompat.RewriteRequired, false), true},
+		{"incompatible", typeChange(typecompat.Incompatible, true), true},
+		{"check added", Change{Action: Added, Object: ObjectCheck}, true},
+		{"foreign key added", Change{Action: Added, Object: ObjectForeignKey}, true},
+		{"index added", Change{Action: Added, Object: ObjectIndex}, false},
+		{"index changed", Change{Action: Changed, Object: ObjectIndex}, false},
+		{"index renamed", Change{Action: Renamed, Object: ObjectIndex}, false},
+		{"unique changed", Change{Action: Changed, Object: ObjectUnique}, true},
+		{"index dropped", Change{Action: Dropped, Object: ObjectIndex}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.c.Breaking(); got != tt.want {
+			t.Errorf("%s: Breaking() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	changes := []Change{
+		// a new table's constraints aren't breaking: nothing used it yet
+		{Action: Added, Object: ObjectTable, DB: "app", Schema: "public", Table: "orders"},
+		{Action: Added, Object: ObjectColumn, DB: "app", Schema: "public", Table: "orders", Name: "id"},
+		{Action: Added, Object: ObjectPrimaryKey, DB: "app", Schema: "public", Table: "orders"},
+		// nor is anything in a new schema
+		{Action: Added, Object: ObjectSchema, DB: "app", Schema: "billing"},
+		{Action: Added, Object: ObjectTable, DB: "app", Schema: "billing", Table: "invoices"},
+		{Action: Added, Object: ObjectForeignKey, DB: "app", Schema: "billing", Table: "invoices", Name: "invoices_order_fkey"},
+		// an existing table's are
+		{Action: Added, Object: ObjectCheck, DB: "app", Schema: "public", Table: "users", Name: "users_email_check"},
+		{Action: Dropped, Object: ObjectColumn, DB: "app", Schema: "public", Table: "users", Name: "legacy"},
+		{Action: Dropped, Object: ObjectTable, DB: "crm", Schema: "public", Table: "leads"},
+	}
+
+	sum := Summarize(changes)
+	if got, want := sum.Stats.String(), "9 changes (3 breaking, 6 non-breaking), 4 tables touched"; got != want {
+		t.Errorf("totals = %q, want %q", got, want)
+	}
+	if got, want := sum.ObjectCounts(), "schema +1, table +2 -1, column +1 -1, primary_key +1, check +1, foreign_key +1"; got != want {
+		t.Errorf("object counts = %q, want %q", got, want)
+	}
+
+	if len(sum.Databases) != 2 || sum.Databases[0].Name != "app" || sum.Databases[1].Name != "crm" {
+		t.Fatalf("databases = %+v, want app and crm", sum.Databases)
+	}
+	app := sum.Databases[0]
+	if app.Breaking != 2 || len(app.Schemas) != 2 || app.Schemas[0].Name != "billing" || app.Schemas[1].Name != "public" {
+		t.Errorf("app = %+v, want 2 breaking in schemas billing and public", app)
+	}
+	if billing := app.Schemas[0]; billing.Breaking != 0 || billing.Changes != 3 || billing.TablesTouched != 1 {
+		t.Errorf("billing = %+v, want 3 non-breaking changes to 1 table", billing.Stats)
+	}
+}
@@ -0,0 +1,145 @@
+package snapshot
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Saba101/GoMetaSync/internal/filter"
+)
+
+// DefaultSuppressionsFile is read by the CLI when no --ignore file is given and it exists.
+const DefaultSuppressionsFile = ".gometasyncignore"
+
+// Suppression hides expected drift from the diff. One rule per line in a .gometasyncignore file:
+//
+//	# <kind> <path> [expires=YYYY-MM-DD] [reason...]
+//	index_added   app.public.users.idx_debug_*  expires=2026-01-31  debug index, DEV only
+//	column_*      app.public.orders.status      migration 0042 pending
+//
+// Kind and path are globs or "re:" patterns (see filter.Match) matched against Change.Kind and Change.Path.
+type Suppression struct {
+	Kind    string
+	Path    string
+	Expires time.Time // zero: never
+	Reason  string
+
+	Source string // file:line the rule came from
+}
+
+func (s Suppression) String() string {
+	out := fmt.Sprintf("%s %s (%s)", s.Kind, s.Path, s.Source)
+	if !s.Expires.IsZero() {
+		out += " expires " + s.Expires.Format(time.DateOnly)
+	}
+	if s.Reason != "" {
+		out += ": " + s.Reason
+	}
+	return out
+}
+
+// Matches reports whether the rule covers the change, ignoring expiry.
+func (s Suppression) Matches(c Change) bool {
+	return filter.Match(s.Kind, c.Kind()) && filter.Match(s.Path, c.Path())
+}
+
+// Expired reports whether the rule has stopped applying at now. A rule expires at the end of its expiry day.
+func (s Suppression) Expired(now time.Time) bool {
+	return !s.Expires.IsZero() && !now.Before(s.Expires.AddDate(0, 0, 1))
+}
+
+// Suppressed is a change hidden by a suppression rule.
+type Suppressed struct {
+	Change Change
+	Rule   Suppression
+}
+
+// LoadSuppressions parses a .gometasyncignore file.
+func LoadSuppressions(path string) ([]Suppression, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []Suppression
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: want <kind> <path> [expires=YYYY-MM-DD] [reason]", path, n)
+		}
+		r := Suppression{Kind: fields[0], Path: fields[1], Source: fmt.Sprintf("%s:%d", path, n)}
+		for _, p := range []string{r.Kind, r.Path} {
+			if err := filter.CheckPattern(p); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+			}
+		}
+		rest := fields[2:]
+		if len(rest) > 0 {
+			if v, ok := strings.CutPrefix(rest[0], "expires="); ok {
+				r.Expires, err = time.Parse(time.DateOnly, v)
+				if err != nil {
+					return nil, fmt.Errorf("%s:%d: bad expiry date: %w", path, n, err)
+				}
+				rest = rest[1:]
+			}
+		}
+		r.Reason = strings.Join(rest, " ")
+		rules = append(rules, r)
+	}
+	return rules, sc.Err()
+}
+
+// Suppress splits changes into the ones still reported and the ones hidden by a live rule.
+// A rule that hides a table or schema also hides the changes inside it, such as the columns of an added table.
+// Expired rules are returned so they can be reported instead of silently ignored.
+func Suppress(changes []Change, rules []Suppression, now time.Time) (kept []Change, suppressed []Suppressed, expired []Suppression) {
+	var live []Suppression
+	for _, r := range rules {
+		if r.Expired(now) {
+			expired = append(expired, r)
+		} else {
+			live = append(live, r)
+		}
+	}
+
+	match := func(c Change) (Suppression, bool) {
+		for _, r := range live {
+			if r.Matches(c) {
+				return r, true
+			}
+		}
+		return Suppression{}, false
+	}
+	containers := map[string]Suppression{} // table or schema path -> rule that hid it
+	for _, c := range changes {
+		if c.Object == ObjectTable || c.Object == ObjectSchema {
+			if r, ok := match(c); ok {
+				containers[c.Group()] = r
+			}
+		}
+	}
+
+	for _, c := range changes {
+		r, ok := match(c)
+		if !ok {
+			r, ok = containers[c.DB+"."+c.Schema]
+		}
+		if !ok && c.Table != "" {
+			r, ok = containers[tablePath(c)]
+		}
+		if ok {
+			suppressed = append(suppressed, Suppressed{Change: c, Rule: r})
+		} else {
+			kept = append(kept, c)
+		}
+	}
+	return kept, suppressed, expired
+}
@@ -0,0 +1,165 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSuppressionMatches(t *testing.T) {
+	dropped := Change{Action: Dropped, Object: ObjectColumn, DB: "app", Schema: "public", Table: "orders", Name: "status"}
+	debugIdx := Change{Action: Added, Object: ObjectIndex, DB: "app", Schema: "public", Table: "users", Name: "idx_debug_email"}
+
+	tests := []struct {
+		kind, path string
+		c          Change
+		want       bool
+	}{
+		{"column_dropped", "app.public.orders.status", dropped, true},
+		{"column_*", "app.public.orders.status", dropped, true},
+		{"column_added", "app.public.orders.status", dropped, false},
+		{"column_*", "app.public.orders.state", dropped, false},
+		{"index_added", "app.public.users.idx_debug_*", debugIdx, true},
+		{"index_added", "re:app\\.public\\.users\\.idx_(debug|tmp)_.*", debugIdx, true},
+		{"index_dropped", "app.public.users.idx_debug_*", debugIdx, false},
+	}
+	for _, tt := range tests {
+		r := Suppression{Kind: tt.kind, Path: tt.path}
+		if got := r.Matches(tt.c); got != tt.want {
+			t.Errorf("%s %s matches %s %s = %v, want %v", tt.kind, tt.path, tt.c.Kind(), tt.c.Path(), got, tt.want)
+		}
+	}
+}
+
+func TestSuppressionExpired(t *testing.T) {
+	r := Suppression{Expires: time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		now  time.Time
+		want bool
+	}{
+		{time.Date(2026, 1, 30, 12, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, 1, 31, 23, 59, 0, 0, time.UTC), false}, // still applies on its expiry day
+		{time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		if got := r.Expired(tt.now); got != tt.want {
+			t.Errorf("Expired(%s) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+	if (Suppression{}).Expired(time.Now()) {
+		t.Error("a rule without expiry expired")
+	}
+}
+
+func TestSuppress(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	changes := []Change{
+		{Action: Dropped, Object: ObjectColumn, DB: "app", Schema: "public", Table: "orders", Name: "status"},
+		{Action: Added, Object: ObjectIndex, DB: "app", Schema: "public", Table: "users", Name: "idx_debug_email"},
+		{Action: Added, Object: ObjectColumn, DB: "app", Schema: "public", Table: "users", Name: "nick"},
+	}
+	rules := []Suppression{
+		{Kind: "column_*", Path: "app.public.orders.status"},
+		{Kind: "index_added", Path: "app.public.users.idx_debug_*", Expires: time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)},
+	}
+
+	kept, suppressed, expired := Suppress(changes, rules, now)
+	if len(kept) != 2 || kept[0].Name != "idx_debug_email" || kept[1].Name != "nick" {
+		t.Errorf("kept = %v, want the index (its rule expired) and nick", kept)
+	}
+	if len(suppressed) != 1 || suppressed[0].Change.Name != "status" || suppressed[0].Rule.Kind != "column_*" {
+		t.Errorf("suppressed = %v, want status by column_*", suppressed)
+	}
+	if len(expired) != 1 || expired[0].Kind != "index_added" {
+		t.Errorf("expired = %v, want the index_added rule", expired)
+	}
+}
+
+func TestLoadSuppressions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Suppression // Source is checked separately
+		wantErr string
+	}{
+		{
+			name: "rules",
+			content: `# comment
+
+index_added   app.public.users.idx_debug_*  expires=2026-01-31  debug index, DEV only
+column_*      app.public.orders.status      migration 0042 pending
+table_dropped app.public.tmp
+`,
+			want: []Suppression{
+				{Kind: "index_added", Path: "app.public.users.idx_debug_*", Expires: time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC), Reason: "debug index, DEV only"},
+				{Kind: "column_*", Path: "app.public.orders.status", Reason: "migration 0042 pending"},
+				{Kind: "table_dropped", Path: "app.public.tmp"},
+			},
+		},
+		{name: "missing path", content: "column_dropped\n", wantErr: ":1: want <kind> <path>"},
+		{name: "bad date", content: "\ncolumn_dropped app.* expires=31/01/2026\n", wantErr: ":2: bad expiry date"},
+		{name: "bad regexp", content: "column_dropped re:app.(\n", wantErr: ":1: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), DefaultSuppressionsFile)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadSuppressions(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d rules, want %d: %v", len(got), len(tt.want), got)
+			}
+			for i, r := range got {
+				if !strings.HasPrefix(r.Source, path+":") {
+					t.Errorf("rule %d source = %q, want %s:<line>", i, r.Source, path)
+				}
+				r.Source = ""
+				if r != tt.want[i] {
+					t.Errorf("rule %d = %+v, want %+v", i, r, tt.want[i])
+				}
+			}
+			if got[0].Source != path+":3" {
+				t.Errorf("first rule source = %q, want line 3", got[0].Source)
+			}
+		})
+	}
+}
+
+func TestSuppressHidesContents(t *testing.T) {
+	changes := []Change{
+		{Action: Added, Object: ObjectTable, DB: "app", Schema: "public", Table: "tmp_import"},
+		{Action: Added, Object: ObjectColumn, DB: "app", Schema: "public", Table: "tmp_import", Name: "id"},
+		{Action: Added, Object: ObjectIndex, DB: "app", Schema: "public", Table: "tmp_import", Name: "tmp_import_id_idx"},
+		{Action: Added, Object: ObjectSchema, DB: "app", Schema: "scratch"},
+		{Action: Added, Object: ObjectTable, DB: "app", Schema: "scratch", Table: "t"},
+		{Action: Added, Object: ObjectColumn, DB: "app", Schema: "scratch", Table: "t", Name: "id"},
+		{Action: Added, Object: ObjectColumn, DB: "app", Schema: "public", Table: "tmp_import_log", Name: "id"},
+	}
+	rules := []Suppression{
+		{Kind: "table_added", Path: "app.public.tmp_*"},
+		{Kind: "schema_added", Path: "app.scratch"},
+	}
+
+	kept, suppressed, _ := Suppress(changes, rules, time.Now())
+	if len(kept) != 1 || kept[0].Table != "tmp_import_log" {
+		t.Errorf("kept = %v, want only the column of tmp_import_log", kept)
+	}
+	if len(suppressed) != 6 {
+		t.Fatalf("suppressed %d changes, want 6: %v", len(suppressed), suppressed)
+	}
+	if r := suppressed[5].Rule; r.Kind != "schema_added" {
+		t.Errorf("scratch.t.id suppressed by %s, want the schema_added rule", r)
+	}
+}
@@ -0,0 +1,200 @@
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+)
+
+// MergeClass classifies a change in a three-way diff by which side made it.
+type MergeClass string
+
+const (
+	LeftOnly    MergeClass = "left_only"   // only the left side changed the object
+	RightOnly   MergeClass = "right_only"  // only the right side changed the object
+	BothSame    MergeClass = "both_same"   // both sides made the same change
+	Conflicting MergeClass = "conflicting" // both sides changed the object, differently
+)
+
+// Merged is one object changed since the base snapshot, with each side's changes to it.
+type Merged struct {
+	Class MergeClass `json:"class"`
+	Path  string     `json:"path"` // path of the object in the base snapshot (new path if added)
+	Left  []Change   `json:"left,omitempty"`
+	Right []Change   `json:"right,omitempty"`
+}
+
+// ThreeWay compares left and right with their common base, like a merge tool: changes are matched
+// per object of the base, so a column one side retyped and the other dropped is a conflict.
+// Suppressions in opts hide changes on either side.
+func ThreeWay(base, left, right *models.Snapshot, opts DiffOptions) []Merged {
+	leftChanges, _, _ := Suppress(Compare(base, left, opts), opts.Suppressions, opts.now())
+	rightChanges, _, _ := Suppress(Compare(base, right, opts), opts.Suppressions, opts.now())
+	l, r := byBaseObject(leftChanges), byBaseObject(rightChanges)
+
+	var keys []string
+	for _, m := range []map[string][]Change{l, r} {
+		for k := range m {
+			if !slices.Contains(keys, k) {
+				keys = append(keys, k)
+			}
+		}
+	}
+	// by path, so an object's changes sit next to its table's
+	slices.SortFunc(keys, func(a, b string) int {
+		ao, ap, _ := strings.Cut(a, " ")
+		bo, bp, _ := strings.Cut(b, " ")
+		if c := strings.Compare(ap, bp); c != 0 {
+			return c
+		}
+		return slices.Index(Objects, Object(ao)) - slices.Index(Objects, Object(bo))
+	})
+
+	var out []Merged
+	for _, k := range keys {
+		m := Merged{Path: k[strings.Index(k, " ")+1:], Left: l[k], Right: r[k]}
+		switch {
+		case len(m.Right) == 0:
+			m.Class = LeftOnly
+		case len(m.Left) == 0:
+			m.Class = RightOnly
+		case sameOutcome(m.Left, m.Right):
+			m.Class = BothSame
+		default:
+			m.Class = Conflicting
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// byBaseObject groups changes by object type and path in the base snapshot, following renames.
+func byBaseObject(changes []Change) map[string][]Change {
+	oldTables := map[string]string{} // new table path -> base name
+	for _, c := range changes {
+		if c.Object == ObjectTable && c.Action == Renamed {
+			oldTables[tablePath(c)] = c.OldName
+		}
+	}
+	out := map[string][]Change{}
+	for _, c := range changes {
+		b := c
+		if old, ok := oldTables[tablePath(c)]; ok {
+			b.Table = old
+		}
+		if c.Action == Renamed && c.Object != ObjectTable {
+			b.Name = c.OldName
+		}
+		key := string(c.Object) + " " + b.Path()
+		out[key] = append(out[key], c)
+	}
+	return out
+}
+
+// sameOutcome reports whether two sides' changes to one object leave it in the same state.
+func sameOutcome(left, right []Change) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for i := range left {
+		a, b := left[i], right[i]
+		if a.Action != b.Action || a.Table != b.Table || a.Name != b.Name {
+			return false
+		}
+		if !sameState(a, b) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameState compares the New sides of two changes to the same object, as Compare would.
+func sameState(a, b Change) bool {
+	switch a.Object {
+	case ObjectCheck:
+		x, _ := a.New.(string)
+		y, _ := b.New.(string)
+		return checkKey(x) == checkKey(y)
+	case ObjectForeignKey:
+		x, _ := a.New.(models.ForeignKey)
+		y, _ := b.New.(models.ForeignKey)
+		return foreignKeyKey(x) == foreignKeyKey(y)
+	case ObjectIndex:
+		x, _ := a.New.(models.Index)
+		y, _ := b.New.(models.Index)
+		return indexKey(x) == indexKey(y)
+	case ObjectTable:
+		// compared by contents; renames were matched by name above
+		x, _ := a.New.(models.TableSnapshot)
+		y, _ := b.New.(models.TableSnapshot)
+		return a.Action == Renamed || len(compareTables(x, y, false)) == 0
+	}
+	return reflect.DeepEqual(a.New, b.New)
+}
+
+// FprintThreeWay writes a three-way diff to w, grouped by class.
+func FprintThreeWay(w io.Writer, merged []Merged) {
+	sections := []struct {
+		class MergeClass
+		title string
+	}{
+		{Conflicting, "⚔️ Conflicts"},
+		{LeftOnly, "⬅️ Left only"},
+		{RightOnly, "➡️ Right only"},
+		{BothSame, "🟰 Both sides, same change"},
+	}
+	var left, right []Change
+	for _, m := range merged {
+		left = append(left, m.Left...)
+		right = append(right, m.Right...)
+	}
+	if len(merged) > 0 {
+		fmt.Fprintf(w, "📊 Left:  %s\n", Summarize(left).Stats)
+		fmt.Fprintf(w, "📊 Right: %s\n\n", Summarize(right).Stats)
+	}
+
+	printed := false
+	for _, s := range sections {
+		var ms []Merged
+		for _, m := range merged {
+			if m.Class == s.class {
+				ms = append(ms, m)
+			}
+		}
+		if len(ms) == 0 {
+			continue
+		}
+		if printed {
+			fmt.Fprintln(w)
+		}
+		printed = true
+		fmt.Fprintf(w, "%s (%d):\n", s.title, len(ms))
+		for _, m := range ms {
+			switch m.Class {
+			case LeftOnly, BothSame:
+				for _, c := range m.Left {
+					fmt.Fprintf(w, "  %s\n", c)
+				}
+			case RightOnly:
+				for _, c := range m.Right {
+					fmt.Fprintf(w, "  %s\n", c)
+				}
+			case Conflicting:
+				fmt.Fprintf(w, "  %s\n", m.Path)
+				for _, c := range m.Left {
+					fmt.Fprintf(w, "    left:  %s\n", c)
+				}
+				for _, c := range m.Right {
+					fmt.Fprintf(w, "    right: %s\n", c)
+				}
+			}
+		}
+	}
+	if !printed {
+		fmt.Fprintln(w, "✅ Neither side changed since the base snapshot")
+	}
+}
@@ -0,0 +1,57 @@
+package snapshot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Saba101/GoMetaSync/internal/models"
+	"github.com/Saba101/GoMetaSync/internal/models/modelstest"
+)
+
+func TestThreeWay(t *testing.T) {
+	typed := func(name string, cols map[string]string) models.TableSnapshot {
+		return models.TableSnapshot{Name: name, Columns: cols}
+	}
+	base := modelstest.Snapshot(typed("users", map[string]string{"id": "integer", "email": "text", "age": "integer", "nick": "text"}))
+	left := modelstest.Snapshot(
+		typed("users", map[string]string{"id": "bigint", "email": "text", "age": "bigint", "bio": "text"}),
+		typed("audit", map[string]string{"id": "integer"}),
+	)
+	right := modelstest.Snapshot(typed("users", map[string]string{"id": "bigint", "age": "numeric", "nick": "text", "bio": "text"}))
+
+	var got []string
+	for _, m := range ThreeWay(base, left, right, DiffOptions{}) {
+		got = append(got, string(m.Class)+" "+m.Path)
+	}
+	want := []string{
+		"left_only app.public.audit",
+		"left_only app.public.audit.id",
+		"conflicting app.public.users.age", // bigint vs numeric
+		"both_same app.public.users.bio",
+		"right_only app.public.users.email",
+		"both_same app.public.users.id",
+		"left_only app.public.users.nick",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ThreeWay:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// A column renamed on one side and retyped on the other is one object changed twice.
+func TestThreeWayFollowsRenames(t *testing.T) {
+	base := modelstest.Snapshot(modelstest.Table("users", "id", "email"))
+	left := modelstest.Snapshot(modelstest.Table("users", "id", "mail"))
+	right := modelstest.Snapshot(models.TableSnapshot{Name: "users", Columns: map[string]string{"id": "text", "email": "character varying"}})
+	opts := DiffOptions{RenameHints: []RenameHint{{Object: ObjectColumn, From: "app.public.users.email", To: "mail"}}}
+
+	merged := ThreeWay(base, left, right, opts)
+	var conflict *Merged
+	for i, m := range merged {
+		if m.Path == "app.public.users.email" {
+			conflict = &merged[i]
+		}
+	}
+	if conflict == nil || conflict.Class != Conflicting || conflict.Left[0].Action != Renamed || conflict.Right[0].Action != Changed {
+		t.Errorf("ThreeWay = %+v, want a conflict on users.email between a rename and a type change", merged)
+	}
+}
@@ -0,0 +1,262 @@
+// Package typecompat classifies Postgres column type changes by what ALTER COLUMN ... TYPE
+// does to the table and its data, and by whether the generated Go field type changes.
+package typecompat
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Saba101/GoMetaSync/internal/gotype"
+)
+
+// Class is how a column type change affects existing data.
+type Class string
+
+const (
+	// Widening accepts every old value and doesn't rewrite the table, e.g. varchar(50) → varchar(100).
+	Widening Class = "widening"
+	// RewriteRequired converts every old value but rewrites the table under an
+	// ACCESS EXCLUSIVE lock, e.g. integer → bigint.
+	RewriteRequired Class = "rewrite-required"
+	// Narrowing rewrites the table and fails, or truncates, on values that don't fit, e.g. bigint → integer.
+	Narrowing Class = "narrowing"
+	// Incompatible needs an explicit USING conversion that fails on most data, e.g. text → integer.
+	Incompatible Class = "incompatible"
+)
+
+// Result classifies one type change.
+type Result struct {
+	Class  Class  `json:"class"`
+	Reason string `json:"reason"`
+
+	OldGoType     string `json:"old_go_type"`
+	NewGoType     string `json:"new_go_type"`
+	GoTypeChanged bool   `json:"go_type_changed"`
+}
+
+// String renders the result for diff output, e.g. "rewrite-required, Go type int → int64".
+func (r Result) String() string {
+	if r.GoTypeChanged {
+		return fmt.Sprintf("%s, Go type %s → %s", r.Class, r.OldGoType, r.NewGoType)
+	}
+	return fmt.Sprintf("%s, Go type %s unchanged", r.Class, r.OldGoType)
+}
+
+// Classify classifies changing a column from oldType to newType. Types are full types as
+// format_type() spells them ("character varying(50)", "numeric(10,2)", "integer[]") or bare
+// information_schema data types, in which case modifiers are unknown and assumed unchanged.
+func Classify(oldType, newType string) Result {
+	o, n := parse(oldType), parse(newType)
+	r := Result{OldGoType: gotype.For(oldType), NewGoType: gotype.For(newType)}
+	r.GoTypeChanged = r.OldGoType != r.NewGoType
+	r.Class, r.Reason = classify(o, n)
+	return r
+}
+
+func classify(o, n pgType) (Class, string) {
+	if o.array != n.array {
+		return Incompatible, "converts between an array and a scalar"
+	}
+	if o.name == n.name {
+		return sameType(o, n)
+	}
+
+	of, nf := family(o.name), family(n.name)
+	switch {
+	case isText(n.name) && of != "string" && len(n.mods) > 0:
+		return Narrowing, "values whose text is longer than the limit fail to convert"
+	case isText(n.name) && of != "string":
+		// every type has a text output function
+		return RewriteRequired, "stores the values as text"
+
+	case of == "string" && nf == "string":
+		// text, varchar and char are binary-compatible; only a length limit can reject values
+		switch {
+		case n.name == "character" || (len(n.mods) > 0 && (len(o.mods) == 0 || n.mods[0] < o.mods[0])):
+			return Narrowing, "longer strings no longer fit"
+		case o.name == "character":
+			return RewriteRequired, "strips the padding of char values"
+		}
+		return Widening, "binary-compatible string types, no rewrite"
+
+	case of == "int" && (nf == "int" || nf == "numeric" || nf == "float"):
+		if nf == "int" && rank(n.name) < rank(o.name) {
+			return Narrowing, "large values are out of range"
+		}
+		if n.name == "real" && o.name != "smallint" || n.name == "double precision" && o.name == "bigint" {
+			return Narrowing, "large integers lose precision as floats"
+		}
+		if nf == "numeric" && len(n.mods) > 0 && n.mods[0]-mod(n.mods, 1) < intDigits[o.name] {
+			return Narrowing, "values with more digits than the precision are out of range"
+		}
+		return RewriteRequired, "every value converts, but the table is rewritten"
+
+	case of == "numeric" && nf == "int":
+		return Narrowing, "fractions are rounded and large values are out of range"
+	case of == "numeric" && nf == "float", of == "float" && nf == "numeric":
+		return Narrowing, "converting between exact and floating-point numbers can change values"
+	case of == "float" && nf == "float":
+		if n.name == "real" {
+			return Narrowing, "double precision values lose precision as real"
+		}
+		return RewriteRequired, "every value converts, but the table is rewritten"
+	case of == "float" && nf == "int":
+		return Narrowing, "fractions are rounded and large values are out of range"
+
+	case of == "time" && nf == "time":
+		switch {
+		case o.name == "date":
+			return RewriteRequired, "dates become midnight timestamps"
+		case n.name == "date":
+			return Narrowing, "drops the time of day"
+		case o.name == "timestamp without time zone" && n.name == "timestamp with time zone":
+			return RewriteRequired, "values are interpreted in the session time zone (no rewrite on PostgreSQL 12+ when it is UTC)"
+		case o.name == "timestamp with time zone" && n.name == "timestamp without time zone":
+			return Narrowing, "drops the time zone; values are shifted to the session time zone"
+		}
+
+	case o.name == "json" && n.name == "jsonb":
+		return RewriteRequired, "parses every value into jsonb; duplicate keys and key order are dropped"
+	case o.name == "jsonb" && n.name == "json":
+		return RewriteRequired, "stores every value as json text"
+	case o.name == "cidr" && n.name == "inet":
+		return Widening, "every cidr is a valid inet, no rewrite"
+	case o.name == "inet" && n.name == "cidr":
+		return Narrowing, "inet values with host bits set are rejected"
+	case o.name == "bit" && n.name == "bit varying", o.name == "bit varying" && n.name == "bit varying" && len(n.mods) == 0:
+		return Widening, "binary-compatible bit string types, no rewrite"
+	}
+	return Incompatible, fmt.Sprintf("no implicit conversion from %s to %s; needs a USING expression that can fail", o.name, n.name)
+}
+
+// sameType classifies a change of modifiers only, e.g. varchar(50) → varchar(100).
+func sameType(o, n pgType) (Class, string) {
+	if slices.Equal(o.mods, n.mods) {
+		return Widening, "same type"
+	}
+	if len(n.mods) == 0 {
+		// dropping a limit or precision accepts every old value
+		if o.name == "character" {
+			return RewriteRequired, "char without a length is char(1)"
+		}
+		return Widening, "removes the limit, no rewrite"
+	}
+	if len(o.mods) == 0 {
+		return Narrowing, "adds a limit that existing values may exceed"
+	}
+
+	switch o.name {
+	case "character varying", "bit varying":
+		if n.mods[0] >= o.mods[0] {
+			return Widening, "raises the length limit, no rewrite"
+		}
+		return Narrowing, "lowers the length limit; longer values no longer fit"
+	case "character", "bit":
+		if n.mods[0] >= o.mods[0] {
+			return RewriteRequired, "fixed-length values are padded to the new length"
+		}
+		return Narrowing, "lowers the length; longer values no longer fit"
+	case "numeric":
+		op, os := o.mods[0], mod(o.mods, 1)
+		np, ns := n.mods[0], mod(n.mods, 1)
+		switch {
+		case ns == os && np >= op:
+			return Widening, "raises the precision at the same scale, no rewrite"
+		case np-ns >= op-os && ns >= os:
+			return RewriteRequired, "every value fits, but a scale change rewrites the table"
+		case ns < os && np-ns >= op-os:
+			return Narrowing, "lowers the scale; values are rounded"
+		}
+		return Narrowing, "lowers the integer digits; large values are out of range"
+	case "timestamp without time zone", "timestamp with time zone", "time without time zone", "time with time zone", "interval":
+		if n.mods[0] >= o.mods[0] {
+			return Widening, "raises the fractional-second precision, no rewrite"
+		}
+		return Narrowing, "lowers the fractional-second precision; values are rounded"
+	}
+	return RewriteRequired, "changes the type modifier"
+}
+
+func mod(mods []int, i int) int {
+	if i < len(mods) {
+		return mods[i]
+	}
+	return 0
+}
+
+// ---------- type names ----------
+
+type pgType struct {
+	name  string // canonical name, e.g. "character varying"
+	mods  []int  // type modifiers, e.g. [10 2] for numeric(10,2)
+	array bool
+}
+
+// aliases maps alternative spellings to the names format_type() uses.
+var aliases = map[string]string{
+	"int": "integer", "int4": "integer", "int2": "smallint", "int8": "bigint",
+	"serial": "integer", "bigserial": "bigint", "smallserial": "smallint",
+	"float4": "real", "float8": "double precision", "float": "double precision",
+	"decimal": "numeric", "bool": "boolean",
+	"varchar": "character varying", "char": "character", "bpchar": "character",
+	"varbit":    "bit varying",
+	"timestamp": "timestamp without time zone", "timestamptz": "timestamp with time zone",
+	"time": "time without time zone", "timetz": "time with time zone",
+}
+
+// parse splits a type such as "timestamp(3) with time zone" or "numeric(10,2)[]".
+func parse(s string) pgType {
+	var t pgType
+	s = strings.ToLower(strings.TrimSpace(s))
+	if strings.HasSuffix(s, "[]") || s == "array" {
+		t.array = true
+		s = strings.TrimSpace(strings.TrimSuffix(s, "[]"))
+	}
+	if open := strings.Index(s, "("); open >= 0 {
+		if end := strings.Index(s[open:], ")"); end >= 0 {
+			for _, m := range strings.Split(s[open+1:open+end], ",") {
+				if v, err := strconv.Atoi(strings.TrimSpace(m)); err == nil {
+					t.mods = append(t.mods, v)
+				}
+			}
+			s = s[:open] + s[open+end+1:]
+		}
+	}
+	s = strings.Join(strings.Fields(s), " ")
+	s = strings.TrimPrefix(s, "pg_catalog.")
+	if a, ok := aliases[s]; ok {
+		s = a
+	}
+	t.name = s
+	return t
+}
+
+func family(name string) string {
+	switch name {
+	case "text", "character varying", "character", "citext", "name":
+		return "string"
+	case "smallint", "integer", "bigint":
+		return "int"
+	case "numeric":
+		return "numeric"
+	case "real", "double precision":
+		return "float"
+	case "date", "timestamp without time zone", "timestamp with time zone":
+		return "time"
+	}
+	return name
+}
+
+func isText(name string) bool {
+	return name == "text" || name == "character varying"
+}
+
+// intDigits is the number of decimal digits of the largest value of each integer type.
+var intDigits = map[string]int{"smallint": 5, "integer": 10, "bigint": 19}
+
+// rank orders integer types by size.
+func rank(name string) int {
+	return slices.Index([]string{"smallint", "integer", "bigint"}, name)
+}
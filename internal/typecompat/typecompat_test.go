@@ -0,0 +1,47 @@
+package typecompat
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		old, new string
+		want     Class
+		goChange bool
+	}{
+		// the typical widening, rewrite and incompatible changes
+		{"character varying(50)", "character varying(100)", Widening, false},
+		{"integer", "bigint", RewriteRequired, true},
+		{"text", "integer", Incompatible, true},
+
+		{"character varying(100)", "character varying(50)", Narrowing, false},
+		{"character varying(50)", "text", Widening, false},
+		{"text", "character varying(20)", Narrowing, false},
+		{"bigint", "integer", Narrowing, true},
+		{"smallint", "integer", RewriteRequired, true},
+		{"integer", "numeric(5,0)", Narrowing, true},
+		{"integer", "numeric(12,2)", RewriteRequired, true},
+		{"numeric(10,2)", "numeric(12,2)", Widening, false},
+		{"numeric(10,2)", "numeric(8,2)", Narrowing, false},
+		{"integer", "text", RewriteRequired, true},
+		{"integer", "character varying", RewriteRequired, true},
+		{"integer", "character varying(3)", Narrowing, true},
+		{"timestamp without time zone", "timestamp with time zone", RewriteRequired, false},
+		{"timestamp with time zone", "date", Narrowing, false},
+		{"json", "jsonb", RewriteRequired, false},
+		{"cidr", "inet", Widening, false},
+		{"integer", "integer[]", Incompatible, true},
+		{"integer", "integer", Widening, false},
+		// bare data types from older snapshots: modifiers unknown
+		{"character varying", "character varying", Widening, false},
+	}
+	for _, tt := range tests {
+		got := Classify(tt.old, tt.new)
+		if got.Class != tt.want {
+			t.Errorf("Classify(%q, %q) = %s (%s), want %s", tt.old, tt.new, got.Class, got.Reason, tt.want)
+		}
+		if got.GoTypeChanged != tt.goChange {
+			t.Errorf("Classify(%q, %q): Go type %s → %s, changed = %v, want %v",
+				tt.old, tt.new, got.OldGoType, got.NewGoType, got.GoTypeChanged, tt.goChange)
+		}
+	}
+}